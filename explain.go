@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Percona-Lab/pt-mysql-config-diff/internal/confreader"
+)
+
+// explanation has the description of a variable and its value in every
+// source, as shown by the explain command.
+type explanation struct {
+	Variable    string
+	Description string `json:",omitempty"`
+	Values      []sourceValue
+}
+
+// sourceValue is the value of a variable in a source and where it was set.
+type sourceValue struct {
+	Source  string
+	Value   interface{}
+	Origins []confreader.Origin `json:",omitempty"`
+}
+
+// explain returns the description and the values of a variable in configs,
+// read from specs. The variable can be given as an option name, like
+// innodb-buffer-pool-size, and abbreviations are expanded like in compare,
+// see knownNames. The description is taken from the first config having one.
+// The passwords of DSN sources are masked.
+func explain(name string, specs []string, configs []confreader.ConfigReader) explanation {
+	name, _ = confreader.CanonicalName(name, knownNames(configs))
+
+	e := explanation{Variable: name}
+	for i, cfg := range configs {
+		if e.Description == "" {
+			e.Description, _ = cfg.Description(name)
+		}
+		value, ok := cfg.Get(name)
+		if !ok {
+			value = "<Missing>"
+		}
+		e.Values = append(e.Values, sourceValue{Source: sourceName(specs[i]), Value: value, Origins: cfg.Provenance(name)})
+	}
+	return e
+}
+
+func (e explanation) printText() {
+	fmt.Println(e.Variable)
+	if e.Description != "" {
+		fmt.Println(e.Description)
+	}
+	fmt.Println()
+
+	var sourceLen int
+	for _, v := range e.Values {
+		if len(v.Source) > sourceLen {
+			sourceLen = len(v.Source)
+		}
+	}
+	for _, v := range e.Values {
+		fmt.Printf("%*s: %v\n", sourceLen, v.Source, v.Value)
+		for _, origin := range v.Origins {
+			fmt.Printf("%*s  %s\n", sourceLen, "", origin)
+		}
+	}
+}
+
+func (e explanation) printJson() {
+	b, _ := json.MarshalIndent(e, "", "  ")
+	fmt.Println(string(b))
+}
+
+// diffDescriptions returns the one-line description of every key in diffs,
+// taken from the first config describing it. Keys without a description are
+// not returned.
+func diffDescriptions(diffs map[string][]interface{}, configs []confreader.ConfigReader) map[string]string {
+	descriptions := make(map[string]string)
+	for key := range diffs {
+		for _, cfg := range configs {
+			if description, ok := cfg.Description(key); ok && description != "" {
+				descriptions[key] = summary(description)
+				break
+			}
+		}
+	}
+	return descriptions
+}
+
+// summary returns the first sentence of a description.
+func summary(description string) string {
+	if i := strings.Index(description, ". "); i >= 0 {
+		return description[:i+1]
+	}
+	return description
+}
+
+// catalogDefaults returns the catalog defaults closest to the server being
+// compared or to --flavor and --server-version. If the version is unknown,
+// the latest version of the flavor is used. They are used to describe the
+// variables when the sources have no descriptions.
+func catalogDefaults(opts *sourceOptions) (confreader.ConfigReader, string) {
+	flavor, version := opts.flavor, opts.serverVersion
+	if opts.server != nil {
+		flavor, version = confreader.ServerVersion(opts.server)
+	}
+	if flavor == "" {
+		flavor = "mysql"
+	}
+
+	name, err := confreader.ClosestCatalogVersion(flavor, version)
+	if err != nil {
+		name = confreader.LatestCatalogVersion(flavor)
+	}
+	cfg, err := confreader.NewCatalogReader(name)
+	if err != nil {
+		return &confreader.Config{ConfigType: "defaults", EntriesMap: make(map[string]interface{})}, name
+	}
+	return cfg, name
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Percona-Lab/pt-mysql-config-diff/internal/confreader"
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestExplain(t *testing.T) {
+	defaults, err := confreader.NewCatalogReader("mysql-8.0.36")
+	tu.IsNil(t, err)
+	cnf := &confreader.Config{
+		ConfigType: "cnf",
+		EntriesMap: map[string]interface{}{"innodb-buffer-pool-size": "8G"},
+		OriginsMap: map[string]confreader.Origin{"innodb-buffer-pool-size": {Path: "my.cnf", Line: 3, Group: "mysqld"}},
+	}
+	configs := canonicalize([]confreader.ConfigReader{cnf, defaults})
+
+	e := explain("innodb-buffer-pool-size", []string{"my.cnf", "defaults:mysql-8.0.36"}, configs)
+	tu.Equals(t, e, explanation{
+		Variable:    "innodb_buffer_pool_size",
+		Description: "The size of the memory buffer InnoDB uses to cache data and indexes of its tables.",
+		Values: []sourceValue{
+			{Source: "my.cnf", Value: "8G", Origins: []confreader.Origin{{Path: "my.cnf", Line: 3, Group: "mysqld"}}},
+			{Source: "defaults:mysql-8.0.36", Value: "134217728"},
+		},
+	})
+
+	// The catalog is a subset of the variables, so like in compare it is not
+	// used to expand abbreviations. Full defaults are.
+	e = explain("innodb-buffer-pool-si", []string{"my.cnf", "defaults:mysql-8.0.36"}, configs)
+	tu.Equals(t, e.Variable, "innodb_buffer_pool_si")
+
+	full, err := confreader.NewDefaultsParser("internal/confreader/testdata/defaults.txt")
+	tu.IsNil(t, err)
+	e = explain("innodb-buffer-pool-si", []string{"defaults.txt"}, canonicalize([]confreader.ConfigReader{full}))
+	tu.Equals(t, e.Variable, "innodb_buffer_pool_size")
+	tu.Equals(t, e.Values, []sourceValue{{Source: "defaults.txt", Value: "134217728"}})
+
+	e = explain("no_such_variable", []string{"my.cnf"}, configs[:1])
+	tu.Equals(t, e, explanation{
+		Variable: "no_such_variable",
+		Values:   []sourceValue{{Source: "my.cnf", Value: "<Missing>"}},
+	})
+}
+
+func TestDiffDescriptions(t *testing.T) {
+	defaults, err := confreader.NewCatalogReader("mysql-5.7.44")
+	tu.IsNil(t, err)
+	configs := canonicalize([]confreader.ConfigReader{defaults})
+	diffs := map[string][]interface{}{
+		"sync_binlog":      {"0", "1"},
+		"binlog_format":    {"STATEMENT", "ROW"},
+		"unknown_variable": {"1", "<Missing>"},
+	}
+
+	tu.Equals(t, diffDescriptions(diffs, configs), map[string]string{
+		"sync_binlog":   "Synchronously flush binary log to disk after every #th write to the file.",
+		"binlog_format": "What form of binary logging the master will use: either ROW for row-based binary logging, STATEMENT for statement-based binary logging, or MIXED.",
+	})
+}
+
+func TestCatalogDefaults(t *testing.T) {
+	_, name := catalogDefaults(&sourceOptions{flavor: "percona", serverVersion: "8.0.30"})
+	tu.Equals(t, name, "percona-8.0.36-28")
+
+	_, name = catalogDefaults(&sourceOptions{flavor: "mariadb"})
+	tu.Equals(t, name, "mariadb-11.4.2")
+
+	server := &confreader.Config{ConfigType: "mysql", EntriesMap: map[string]interface{}{"version": "5.7.30-log"}}
+	cfg, name := catalogDefaults(&sourceOptions{flavor: "mariadb", server: server})
+	tu.Equals(t, name, "mysql-5.7.44")
+	description, _ := cfg.Description("query_cache_type")
+	tu.Assert(t, description != "", "query_cache_type should have a description")
+}
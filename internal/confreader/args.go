@@ -0,0 +1,348 @@
+package confreader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// printDefaultsHeader is the line mysqld --print-defaults prints before the
+// arguments.
+const printDefaultsHeader = "would have been started with the following arguments:"
+
+// Kinds of option arguments, like in my_getopt.
+const (
+	noArg = iota
+	requiredArg
+	optionalArg
+)
+
+// shortOption is a mysqld short option, like -u.
+type shortOption struct {
+	name string
+	arg  int
+}
+
+// shortOptions has the mysqld short options, by letter.
+var shortOptions = map[string]shortOption{
+	"a": {"ansi", noArg},
+	"b": {"basedir", requiredArg},
+	"C": {"character-set-server", requiredArg},
+	"h": {"datadir", requiredArg},
+	"P": {"port", requiredArg},
+	"t": {"tmpdir", requiredArg},
+	"T": {"exit-info", optionalArg},
+	"u": {"user", requiredArg},
+	"W": {"log-warnings", optionalArg},
+}
+
+// optionalValueOptions are the mysqld options whose value is optional. Like
+// in my_getopt, their value must be attached, as in --log-bin=mysql-bin.
+var optionalValueOptions = map[string]bool{
+	"debug":           true,
+	"event_scheduler": true,
+	"exit_info":       true,
+	"log_bin":         true,
+	"log_warnings":    true,
+}
+
+// boolOptions are mysqld boolean options that are not in the defaults
+// catalog, where boolean options have a TRUE or FALSE default. See takesValue.
+var boolOptions = map[string]bool{
+	"allow_suspicious_udfs": true,
+	"ansi":                  true,
+	"bootstrap":             true,
+	"console":               true,
+	"core_file":             true,
+	"daemonize":             true,
+	"external_locking":      true,
+	"flush":                 true,
+	"gdb":                   true,
+	"help":                  true,
+	"initialize":            true,
+	"initialize_insecure":   true,
+	"large_pages":           true,
+	"memlock":               true,
+	"no_defaults":           true,
+	"print_defaults":        true,
+	"standalone":            true,
+	"symbolic_links":        true,
+	"validate_config":       true,
+	"verbose":               true,
+	"version":               true,
+}
+
+var (
+	catalogBoolOptions     map[string]bool
+	catalogBoolOptionsOnce sync.Once
+)
+
+// printDefaultsArgRe matches the start of an argument in the line printed by
+// mysqld --print-defaults, where every argument is followed by a space. See
+// splitPrintDefaults.
+var printDefaultsArgRe = regexp.MustCompile(` --[A-Za-z][A-Za-z0-9_-]*`)
+
+// NewPrintDefaultsReader reads the arguments printed by my_print_defaults
+// (one per line) or by mysqld --print-defaults (all in the same line).
+func NewPrintDefaultsReader(filename string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read print defaults file")
+	}
+	defer f.Close()
+
+	return NewPrintDefaultsReaderFrom(f, filename)
+}
+
+// NewPrintDefaultsReaderFrom reads my_print_defaults or mysqld --print-defaults
+// output from r. filename is used in error messages and origins.
+func NewPrintDefaultsReaderFrom(r io.Reader, filename string) (ConfigReader, error) {
+	var options []option
+	s := bufio.NewScanner(r)
+	lineNo := 0
+
+	afterHeader := false
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasSuffix(line, printDefaultsHeader) {
+			afterHeader = true
+			continue
+		}
+		if !strings.HasPrefix(line, "-") {
+			return nil, errors.Errorf("%s:%d: invalid argument %q", filename, lineNo, line)
+		}
+
+		// my_print_defaults prints an argument per line, while mysqld
+		// --print-defaults prints all of them in the line after its header.
+		args := []string{line}
+		if afterHeader {
+			args = splitPrintDefaults(line)
+		}
+		for _, arg := range args {
+			name, value := parseArg(arg)
+			options = append(options, option{Name: name, Value: value, File: filename, Line: lineNo})
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return newOptionsConfig("args", options), nil
+}
+
+// splitPrintDefaults splits the arguments printed by mysqld --print-defaults.
+// Values are printed as is, without quotes, so a value can have spaces, like
+// in --init-connect=SET NAMES utf8mb4 --max-connections=500. A new argument
+// starts where a space is followed by an option name, so a value only has to
+// avoid " --name=" or " --name " to be read as printed.
+func splitPrintDefaults(line string) []string {
+	starts := []int{0}
+	for _, m := range printDefaultsArgRe.FindAllStringIndex(line, -1) {
+		if m[1] == len(line) || line[m[1]] == '=' || line[m[1]] == ' ' {
+			starts = append(starts, m[0]+1)
+		}
+	}
+
+	args := make([]string, 0, len(starts))
+	for i, start := range starts {
+		end := len(line)
+		if i+1 < len(starts) {
+			end = starts[i+1] - 1
+		}
+		args = append(args, line[start:end])
+	}
+	return args
+}
+
+// parseArg returns the name and value of a --name=value argument. Arguments
+// without a value, like --skip-name-resolve, are returned as ON.
+func parseArg(arg string) (string, string) {
+	arg = strings.TrimLeft(arg, "-")
+	if i := strings.IndexByte(arg, '='); i >= 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, "ON"
+}
+
+// NewCmdlineReader reads the mysqld arguments saved from /proc/<pid>/cmdline,
+// where the arguments are separated by NUL characters.
+func NewCmdlineReader(filename string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read cmdline file")
+	}
+	defer f.Close()
+
+	return NewCmdlineReaderFrom(f, filename)
+}
+
+// NewCmdlineReaderFrom reads the mysqld arguments saved from /proc/<pid>/cmdline
+// from r. filename is used in origins.
+func NewCmdlineReaderFrom(r io.Reader, filename string) (ConfigReader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	args := strings.Split(string(bytes.TrimRight(data, "\x00")), "\x00")
+	options, err := parseCommandLine(args, filename, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOptionsConfig("args", options), nil
+}
+
+// NewArgsReader reads the mysqld arguments in a command line like
+// "/usr/sbin/mysqld --port=3307 --skip-name-resolve". Words are split and
+// quotes are removed like a shell does.
+func NewArgsReader(cmdline string) (ConfigReader, error) {
+	args, err := splitWords(cmdline)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := parseCommandLine(args, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOptionsConfig("args", options), nil
+}
+
+// parseCommandLine returns the options in the arguments of a command line. The
+// first argument is skipped if it is the program name. Like in mysqld, the
+// value of an option can be the next argument, as in --datadir /var/lib/mysql,
+// unless the option is boolean, and short options like -u mysql or -P3307 are
+// supported. Options with an optional value, like --log-bin or -W, only take
+// it when it is attached, as in --log-bin=mysql-bin or -W2.
+func parseCommandLine(args []string, filename string, line int) ([]option, error) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+
+	var options []option
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var name, value string
+
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			name, value = parseArg(arg)
+			if !strings.Contains(arg, "=") && takesValue(name) && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				value = args[i]
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			short, ok := shortOptions[arg[1:2]]
+			if !ok {
+				return nil, fmt.Errorf("unknown short option %q", arg)
+			}
+			name, value = short.name, arg[2:]
+			switch {
+			case short.arg == noArg && value != "":
+				return nil, fmt.Errorf("option %q doesn't take a value", arg[:2])
+			case short.arg == requiredArg && value == "":
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("option %q requires a value", arg)
+				}
+				i++
+				value = args[i]
+			case value == "":
+				value = "ON"
+			}
+		default:
+			return nil, fmt.Errorf("invalid argument %q", arg)
+		}
+
+		options = append(options, option{Name: name, Value: value, File: filename, Line: line})
+	}
+
+	return options, nil
+}
+
+// takesValue returns true if the next argument can be the value of a long
+// option. Like in mysqld, it can't for boolean options, including the ones
+// having a skip-, enable- or disable- prefix, or for options whose value is
+// optional. Other options, even unknown ones, take it.
+func takesValue(name string) bool {
+	name, implied := CanonicalName(name, nil)
+	if implied != nil || optionalValueOptions[name] || boolOptions[name] {
+		return false
+	}
+	catalogBoolOptionsOnce.Do(func() {
+		catalogBoolOptions = make(map[string]bool)
+		for _, version := range CatalogVersions() {
+			cfg, err := NewCatalogReader(version)
+			if err != nil {
+				continue
+			}
+			for key, value := range cfg.Entries() {
+				if value == "TRUE" || value == "FALSE" {
+					name, _ := CanonicalName(key, nil)
+					catalogBoolOptions[name] = true
+				}
+			}
+		}
+	})
+	return !catalogBoolOptions[name]
+}
+
+// splitWords splits s into words like a shell does. Words are separated by
+// spaces, quotes group words and are removed, and a backslash escapes the next
+// character except inside single quotes.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord, escape := false, false
+
+	for _, c := range s {
+		switch {
+		case escape:
+			word.WriteRune(c)
+			escape = false
+		case c == '\\' && quote != '\'':
+			escape, inWord = true, true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escape {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,162 @@
+package confreader
+
+import (
+	"strings"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestPrintDefaultsReader(t *testing.T) {
+	wantEntries := map[string]interface{}{
+		"datadir":           "/var/lib/mysql",
+		"socket":            "/var/lib/mysql/mysql.sock",
+		"max-connections":   "500",
+		"skip-name-resolve": "ON",
+		"replicate-do-db":   []string{"db1", "db2"},
+		"init-connect":      "SET NAMES utf8mb4",
+	}
+
+	for _, filename := range []string{
+		"testdata/printdefaults/my_print_defaults.txt",
+		"testdata/printdefaults/print-defaults.txt",
+	} {
+		cnf, err := NewPrintDefaultsReader(filename)
+		tu.IsNil(t, err)
+		tu.Equals(t, cnf.Type(), "args")
+		tu.Equals(t, cnf.Entries(), wantEntries)
+	}
+
+	cnf, err := NewPrintDefaultsReader("testdata/printdefaults/my_print_defaults.txt")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Provenance("max-connections"), []Origin{
+		{Path: "testdata/printdefaults/my_print_defaults.txt", Line: 3, Overridden: true},
+		{Path: "testdata/printdefaults/my_print_defaults.txt", Line: 7},
+	})
+
+	_, err = NewPrintDefaultsReaderFrom(strings.NewReader("[mysqld]\nmax_connections=10\n"), "my.cnf")
+	tu.NotNil(t, err)
+}
+
+func TestSplitPrintDefaults(t *testing.T) {
+	tests := map[string][]string{
+		"--port=3306":                           {"--port=3306"},
+		"--port=3306 --skip-name-resolve":       {"--port=3306", "--skip-name-resolve"},
+		"--skip-name-resolve --port=3306":       {"--skip-name-resolve", "--port=3306"},
+		"--init-connect=SET a=1 -- x --b=2":     {"--init-connect=SET a=1 -- x", "--b=2"},
+		"--init-connect=SELECT 1 --1 --log-bin": {"--init-connect=SELECT 1 --1", "--log-bin"},
+		"--init-connect=SET @a='--b' --c":       {"--init-connect=SET @a='--b'", "--c"},
+	}
+	for line, want := range tests {
+		tu.Equals(t, splitPrintDefaults(line), want)
+	}
+
+	// my_print_defaults prints one argument per line, so values are not split.
+	cnf, err := NewPrintDefaultsReaderFrom(strings.NewReader("--init-connect=SET NAMES utf8mb4 --port=1\n"), "my_print_defaults")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{"init-connect": "SET NAMES utf8mb4 --port=1"})
+}
+
+func TestParseArg(t *testing.T) {
+	tests := []struct {
+		arg, name, value string
+	}{
+		{"--port=3306", "port", "3306"},
+		{"--skip-name-resolve", "skip-name-resolve", "ON"},
+		{"--sql-mode=", "sql-mode", ""},
+		{"--init-connect=SET a=1", "init-connect", "SET a=1"},
+	}
+	for _, test := range tests {
+		name, value := parseArg(test.arg)
+		tu.Equals(t, name, test.name)
+		tu.Equals(t, value, test.value)
+	}
+}
+
+func TestCmdlineReader(t *testing.T) {
+	cnf, err := NewCmdlineReader("testdata/cmdline")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"basedir":      "/usr",
+		"datadir":      "/var/lib/mysql",
+		"plugin-dir":   "/usr/lib64/mysql/plugin",
+		"user":         "mysql",
+		"log-error":    "/var/log/mysqld.log",
+		"skip-log-bin": "ON",
+	})
+}
+
+func TestArgsReader(t *testing.T) {
+	cnf, err := NewArgsReader(`mysqld --port 3307 -P3308 --init-connect="SET NAMES utf8mb4" --skip-name-resolve`)
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"port":              "3308",
+		"init-connect":      "SET NAMES utf8mb4",
+		"skip-name-resolve": "ON",
+	})
+
+	_, err = NewArgsReader(`--init-connect="SET NAMES utf8mb4`)
+	tu.NotNil(t, err)
+
+	_, err = NewArgsReader(`--port=3306 -x`)
+	tu.NotNil(t, err)
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := map[string][]string{
+		`a  b`:              {"a", "b"},
+		`--a='x y' "b c"`:   {"--a=x y", "b c"},
+		`a\ b 'c\d' "e\"f"`: {"a b", `c\d`, `e"f`},
+		`--a= ''`:           {"--a=", ""},
+		"":                  nil,
+	}
+	for s, want := range tests {
+		got, err := splitWords(s)
+		tu.IsNil(t, err)
+		tu.Equals(t, got, want)
+	}
+}
+
+func TestParseCommandLine(t *testing.T) {
+	args := []string{"mysqld", "--datadir", "/data", "--skip-grant-tables", "--innodb-file-per-table", "-a", "-t", "/tmp",
+		"-W", "--log-bin", "--event-scheduler", "--max-connections", "500", "-W2"}
+	options, err := parseCommandLine(args, "", 0)
+	tu.IsNil(t, err)
+
+	var got [][2]string
+	for _, opt := range options {
+		got = append(got, [2]string{opt.Name, opt.Value})
+	}
+	tu.Equals(t, got, [][2]string{
+		{"datadir", "/data"},
+		{"skip-grant-tables", "ON"},
+		{"innodb-file-per-table", "ON"},
+		{"ansi", "ON"},
+		{"tmpdir", "/tmp"},
+		{"log-warnings", "ON"},
+		{"log-bin", "ON"},
+		{"event-scheduler", "ON"},
+		{"max-connections", "500"},
+		{"log-warnings", "2"},
+	})
+
+	// Options that are not known to be boolean, even unknown ones, take the
+	// next argument.
+	options, err = parseCommandLine([]string{"--max-binlog-size", "100M", "--port=3306", "--some-plugin-option", "x"}, "", 0)
+	tu.IsNil(t, err)
+	tu.Equals(t, len(options), 3)
+	tu.Equals(t, options[0].Value, "100M")
+	tu.Equals(t, options[2].Value, "x")
+
+	// Boolean options and options with an optional value don't.
+	_, err = parseCommandLine([]string{"--skip-name-resolve", "mysql"}, "", 0)
+	tu.NotNil(t, err)
+	_, err = parseCommandLine([]string{"--local-infile", "mysql"}, "", 0)
+	tu.NotNil(t, err)
+	_, err = parseCommandLine([]string{"--core-file", "mysql"}, "", 0)
+	tu.NotNil(t, err)
+	_, err = parseCommandLine([]string{"--log-bin", "mysql-bin"}, "", 0)
+	tu.NotNil(t, err)
+	_, err = parseCommandLine([]string{"-ab"}, "", 0)
+	tu.NotNil(t, err)
+}
@@ -0,0 +1,187 @@
+package confreader
+
+import (
+	"sort"
+	"strings"
+)
+
+// Variables whose names start with one of the option prefixes handled by
+// CanonicalName but that are real variables, shown as is by SHOW VARIABLES.
+var prefixedVariables = map[string]bool{
+	"skip_external_locking": true,
+	"skip_name_resolve":     true,
+	"skip_networking":       true,
+	"skip_replica_start":    true,
+	"skip_show_database":    true,
+	"skip_slave_start":      true,
+}
+
+// Options that can be specified several times. Each occurrence adds a value
+// instead of replacing the previous one.
+var cumulativeOptions = map[string]bool{
+	"binlog_do_db":                  true,
+	"binlog_ignore_db":              true,
+	"performance_schema_instrument": true,
+	"plugin_load_add":               true,
+	"replicate_do_db":               true,
+	"replicate_do_table":            true,
+	"replicate_ignore_db":           true,
+	"replicate_ignore_table":        true,
+	"replicate_rewrite_db":          true,
+	"replicate_wild_do_table":       true,
+	"replicate_wild_ignore_table":   true,
+}
+
+// IsCumulative returns true if the option can be specified several times and
+// all its values are used. The values of these options are stored as []string.
+func IsCumulative(name string) bool {
+	name, _ = CanonicalName(name, nil)
+	return cumulativeOptions[name]
+}
+
+// CanonicalName returns the name an option has in SHOW VARIABLES and the value
+// it implies, if any. Dashes are replaced by underscores and the loose-, skip-,
+// enable-, disable- and maximum- prefixes are handled like mysqld does:
+//
+//	loose-innodb_numa_interleave -> innodb_numa_interleave
+//	skip-log-bin                 -> log_bin = OFF
+//	enable-named-pipe            -> named_pipe = ON
+//	disable-log-bin              -> log_bin = OFF
+//	maximum-sort-buffer-size     -> maximum_sort_buffer_size
+//
+// Names not found in known are expanded if they are an unambiguous prefix of
+// a known name, since mysqld accepts abbreviated option names.
+// The returned value is nil if the prefix doesn't imply a value.
+func CanonicalName(name string, known map[string]bool) (string, interface{}) {
+	name = strings.Replace(strings.ToLower(strings.TrimSpace(name)), "-", "_", -1)
+	name = strings.TrimPrefix(name, "loose_")
+
+	if known[name] || prefixedVariables[name] {
+		return name, nil
+	}
+
+	switch {
+	case strings.HasPrefix(name, "skip_"):
+		return expandName(strings.TrimPrefix(name, "skip_"), known), "OFF"
+	case strings.HasPrefix(name, "disable_"):
+		return expandName(strings.TrimPrefix(name, "disable_"), known), "OFF"
+	case strings.HasPrefix(name, "enable_"):
+		return expandName(strings.TrimPrefix(name, "enable_"), known), "ON"
+	case strings.HasPrefix(name, "maximum_"):
+		return "maximum_" + expandName(strings.TrimPrefix(name, "maximum_"), known), nil
+	}
+
+	return expandName(name, known), nil
+}
+
+// expandName returns the only name in known having name as prefix or name
+// itself if there is no such name or it is ambiguous.
+func expandName(name string, known map[string]bool) string {
+	if known[name] {
+		return name
+	}
+	match := ""
+	for k := range known {
+		if !strings.HasPrefix(k, name) {
+			continue
+		}
+		if match != "" {
+			return name
+		}
+		match = k
+	}
+	if match == "" {
+		return name
+	}
+	return match
+}
+
+// Canonicalize returns a copy of cfg having all its keys replaced by their
+// canonical names. See CanonicalName.
+// If several keys have the same canonical name, cumulative options have their
+// values merged. Otherwise, like in mysqld, the key set last wins when the
+// read order is known (see Config.Order) and the one already having the
+// canonical form wins when it isn't.
+func Canonicalize(cfg ConfigReader, known map[string]bool) ConfigReader {
+	cnf := &Config{
+		ConfigType:    cfg.Type(),
+		EntriesMap:    make(map[string]interface{}),
+		OriginsMap:    make(map[string]Origin),
+		ProvenanceMap: make(map[string][]Origin),
+	}
+	if c, ok := cfg.(*Config); ok {
+		cnf.Version, cnf.Subset = c.Version, c.Subset
+	}
+	exact := make(map[string]bool)
+
+	keys, ordered := readOrder(cfg)
+
+	for _, key := range keys {
+		name, val := CanonicalName(key, known)
+		if val == nil {
+			val, _ = cfg.Get(key)
+		}
+		if values, ok := val.([]string); ok {
+			if prev, ok := cnf.EntriesMap[name].([]string); ok {
+				values = append(append([]string{}, prev...), values...)
+			}
+			cnf.EntriesMap[name] = values
+			cnf.ProvenanceMap[name] = append(cnf.ProvenanceMap[name], cfg.Provenance(key)...)
+			continue
+		}
+		if exact[name] && !ordered {
+			continue
+		}
+		provenance := cfg.Provenance(key)
+		if _, seen := cnf.EntriesMap[name]; seen && ordered {
+			provenance = append(overridden(cnf.ProvenanceMap[name]), provenance...)
+			delete(cnf.OriginsMap, name)
+		}
+		cnf.EntriesMap[name] = val
+		exact[name] = name == key
+		if provenance != nil {
+			cnf.ProvenanceMap[name] = provenance
+		}
+		if origin, ok := cfg.Origin(key); ok {
+			cnf.OriginsMap[name] = origin
+		}
+		if description, ok := cfg.Description(key); ok {
+			if cnf.DescriptionsMap == nil {
+				cnf.DescriptionsMap = make(map[string]string)
+			}
+			cnf.DescriptionsMap[name] = description
+		}
+	}
+
+	return cnf
+}
+
+// readOrder returns the keys of cfg in the order they were last set and true
+// if that order is known. Otherwise, the keys are returned sorted. Keys
+// missing from the order come first.
+func readOrder(cfg ConfigReader) ([]string, bool) {
+	keys := cfg.Keys()
+	sort.Strings(keys)
+
+	c, ok := cfg.(*Config)
+	if !ok || len(c.Order) == 0 {
+		return keys, false
+	}
+	position := make(map[string]int)
+	for i, key := range c.Order {
+		position[key] = i + 1
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return position[keys[i]] < position[keys[j]] })
+	return keys, true
+}
+
+// overridden returns a copy of provenance having all its origins marked as
+// Overridden.
+func overridden(provenance []Origin) []Origin {
+	result := make([]Origin, len(provenance))
+	for i, origin := range provenance {
+		origin.Overridden = true
+		result[i] = origin
+	}
+	return result
+}
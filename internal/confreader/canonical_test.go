@@ -0,0 +1,100 @@
+package confreader
+
+import (
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestCanonicalName(t *testing.T) {
+	known := map[string]bool{
+		"innodb_numa_interleave":              true,
+		"innodb_buffer_pool_size":             true,
+		"innodb_buffer_pool_dump_at_shutdown": true,
+		"named_pipe":                          true,
+		"log_bin":                             true,
+		"skip_grant_tables":                   true,
+	}
+
+	tests := []struct {
+		name     string
+		wantName string
+		wantVal  interface{}
+	}{
+		{"pid-file", "pid_file", nil},
+		{"skip-name-resolve", "skip_name_resolve", nil},
+		{"skip-grant-tables", "skip_grant_tables", nil},
+		{"loose-innodb_numa_interleave", "innodb_numa_interleave", nil},
+		{"enable-named-pipe", "named_pipe", "ON"},
+		{"disable-log-bin", "log_bin", "OFF"},
+		{"skip-log-bin", "log_bin", "OFF"},
+		{"maximum-sort-buffer-size", "maximum_sort_buffer_size", nil},
+		{"innodb-buffer-pool-si", "innodb_buffer_pool_size", nil},
+		{"innodb_buffer_pool", "innodb_buffer_pool", nil},
+	}
+
+	for _, test := range tests {
+		name, val := CanonicalName(test.name, known)
+		tu.Equals(t, name, test.wantName)
+		tu.Equals(t, val, test.wantVal)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	cnf := &Config{
+		ConfigType: "cnf",
+		EntriesMap: map[string]interface{}{
+			"pid-file":        "/var/run/mysqld/mysqld.pid",
+			"max_connections": "100",
+			"max-connections": "200",
+			"skip-log-bin":    "true",
+			"replicate-do-db": []string{"db1", "db3"},
+			"replicate_do_db": []string{"db2"},
+		},
+	}
+
+	want := map[string]interface{}{
+		"pid_file":        "/var/run/mysqld/mysqld.pid",
+		"max_connections": "100",
+		"log_bin":         "OFF",
+		"replicate_do_db": []string{"db1", "db3", "db2"},
+	}
+
+	got := Canonicalize(cnf, nil)
+	tu.Equals(t, got.Type(), "cnf")
+	tu.Equals(t, got.Entries(), want)
+}
+
+func TestCanonicalizeReadOrder(t *testing.T) {
+	rds := &Config{
+		ConfigType: "rds",
+		EntriesMap: map[string]interface{}{
+			"max_connections":   "100",
+			"max-connections":   "200",
+			"innodb_buffer_poo": "1G",
+			"long_query_time":   "2",
+		},
+		OriginsMap: map[string]Origin{
+			"max_connections": {Path: "params.json"},
+			"max-connections": {Path: "params.json", Line: 2},
+		},
+		Order: []string{"max_connections", "innodb_buffer_poo", "max-connections"},
+	}
+	known := map[string]bool{"innodb_buffer_pool_size": true}
+
+	got := Canonicalize(rds, known)
+	tu.Equals(t, got.Entries(), map[string]interface{}{
+		"max_connections":         "200",
+		"innodb_buffer_pool_size": "1G",
+		"long_query_time":         "2",
+	})
+	tu.Equals(t, got.Provenance("max_connections"), []Origin{
+		{Path: "params.json", Overridden: true},
+		{Path: "params.json", Line: 2},
+	})
+
+	// Without a read order, the canonical spelling wins.
+	rds.Order = nil
+	max, _ := Canonicalize(rds, known).Get("max_connections")
+	tu.Equals(t, max, "100")
+}
@@ -0,0 +1,268 @@
+package confreader
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// catalogFiles has the defaults of several server versions, as printed by
+// mysqld --verbose --help. They are a curated subset: only the variables that
+// are commonly tuned or whose defaults changed between versions are included.
+// The defaults that depend on the host are marked as HostDependent. make
+// catalog regenerates the files with every variable, running the server
+// docker images. Files are named <flavor>-<version>.txt.
+//
+//go:embed catalog/*.txt
+var catalogFiles embed.FS
+
+// HostDependent is the value of the catalog defaults that depend on the host
+// mysqld runs on, like its CPUs, memory or file system, or on how it was
+// built. See MarkHostDependent.
+const HostDependent = "(host dependent)"
+
+// hostDependentDefaults are the variables whose default depends on the host in
+// all versions.
+var hostDependentDefaults = map[string]bool{
+	"basedir":                true,
+	"character_sets_dir":     true,
+	"datadir":                true,
+	"general_log_file":       true,
+	"lc_messages_dir":        true,
+	"lower_case_table_names": true,
+	"open_files_limit":       true,
+	"pid_file":               true,
+	"plugin_dir":             true,
+	"replica_load_tmpdir":    true,
+	"secure_file_priv":       true,
+	"slave_load_tmpdir":      true,
+	"slow_query_log_file":    true,
+	"socket":                 true,
+	"table_definition_cache": true,
+	"table_open_cache":       true,
+	"thread_pool_size":       true,
+	"tmpdir":                 true,
+}
+
+// hostDependentDefaults84 are the variables whose default depends on the CPUs
+// or the memory of the host since MySQL and Percona Server 8.4.
+var hostDependentDefaults84 = map[string]bool{
+	"innodb_buffer_pool_in_core_file": true,
+	"innodb_buffer_pool_instances":    true,
+	"innodb_flush_method":             true,
+	"innodb_page_cleaners":            true,
+	"innodb_parallel_read_threads":    true,
+	"innodb_purge_threads":            true,
+	"innodb_read_io_threads":          true,
+	"temptable_max_ram":               true,
+}
+
+// CatalogFlavors are the server flavors in the defaults catalog.
+var CatalogFlavors = []string{"mysql", "percona", "mariadb"}
+
+// CatalogVersions returns the names of the versions in the defaults catalog,
+// like mysql-8.0.36, sorted by flavor and version.
+func CatalogVersions() []string {
+	entries, _ := catalogFiles.ReadDir("catalog")
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".txt"))
+	}
+	sort.Slice(names, func(i, j int) bool {
+		fi, vi := splitCatalogName(names[i])
+		fj, vj := splitCatalogName(names[j])
+		if fi != fj {
+			return fi < fj
+		}
+		return compareVersions(versionNumbers(vi), versionNumbers(vj)) < 0
+	})
+	return names
+}
+
+// ClosestCatalogVersion returns the name of the catalog version closest to a
+// server version, like 8.0.35-27. Only the versions of the same flavor and
+// major.minor series are considered: the closest patch release is chosen,
+// the older one in case of a tie. If version has no patch number, the latest
+// release of the series is chosen.
+func ClosestCatalogVersion(flavor, version string) (string, error) {
+	want := versionNumbers(version)
+	if len(want) < 2 {
+		return "", fmt.Errorf("invalid server version %q", version)
+	}
+
+	best, bestDistance := "", -1
+	for _, name := range CatalogVersions() {
+		f, v := splitCatalogName(name)
+		have := versionNumbers(v)
+		if f != flavor || len(have) < 3 || have[0] != want[0] || have[1] != want[1] {
+			continue
+		}
+		distance := 0
+		if len(want) > 2 {
+			distance = have[2] - want[2]
+			if distance < 0 {
+				distance = -distance
+			}
+		}
+		// Names are sorted, so on a tie the older version is kept, unless
+		// there is no patch number to match.
+		if bestDistance < 0 || distance < bestDistance || (len(want) == 2 && distance == bestDistance) {
+			best, bestDistance = name, distance
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("there are no %s %d.%d defaults in the catalog. Known versions: %s",
+			flavor, want[0], want[1], strings.Join(CatalogVersions(), ", "))
+	}
+	return best, nil
+}
+
+// LatestCatalogVersion returns the name of the latest version of a flavor in
+// the defaults catalog, or an empty string if the flavor is unknown.
+func LatestCatalogVersion(flavor string) string {
+	latest := ""
+	for _, name := range CatalogVersions() {
+		if f, _ := splitCatalogName(name); f == flavor {
+			latest = name
+		}
+	}
+	return latest
+}
+
+// NewCatalogReader returns the defaults of a version in the catalog, like
+// mysql-8.0.36. See CatalogVersions and ClosestCatalogVersion. The config is a
+// Subset of the server variables.
+func NewCatalogReader(name string) (ConfigReader, error) {
+	f, err := catalogFiles.Open("catalog/" + name + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("there are no %s defaults in the catalog. Known versions: %s",
+			name, strings.Join(CatalogVersions(), ", "))
+	}
+	defer f.Close()
+
+	cnf, err := parseFile(f, "catalog/"+name+".txt")
+	if err != nil {
+		return nil, err
+	}
+	cnf.(*Config).Subset = true
+	return cnf, nil
+}
+
+// MarkHostDependent replaces the host dependent values in the defaults table
+// of the mysqld --verbose --help output with HostDependent, so the catalog
+// doesn't have the values of the machine that generated it.
+func MarkHostDependent(out []byte) []byte {
+	var buf bytes.Buffer
+	s := bufio.NewScanner(bytes.NewReader(out))
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	version := ""
+	inTable, marked := false, false
+	valueColumn := 0
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case !inTable:
+			if m := helpVersionRe.FindStringSubmatch(line); m != nil && version == "" {
+				version = m[1]
+			}
+			if strings.HasPrefix(line, "-----") {
+				inTable = true
+				valueColumn = strings.IndexByte(line, ' ') + 1
+			}
+		case strings.TrimSpace(line) == "":
+			inTable = false
+		case line[0] == ' ' || line[0] == '\t':
+			// The wrapped value of a marked variable is dropped.
+			if marked {
+				continue
+			}
+		default:
+			name := line
+			if i := strings.IndexAny(line, " \t"); i >= 0 {
+				name = line[:i]
+			}
+			marked = isHostDependent(version, strings.Replace(name, "-", "_", -1))
+			if marked {
+				pad := valueColumn - len(name)
+				if pad < 1 {
+					pad = 1
+				}
+				line = name + strings.Repeat(" ", pad) + HostDependent
+			}
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// isHostDependent returns true if the default of a variable depends on the
+// host in a server version, like 8.4.0 or 10.11.7-MariaDB.
+func isHostDependent(version, name string) bool {
+	if hostDependentDefaults[name] {
+		return true
+	}
+	v := versionNumbers(version)
+	mysql84 := !strings.Contains(strings.ToLower(version), "mariadb") && len(v) >= 2 && (v[0] > 8 || (v[0] == 8 && v[1] >= 4))
+	return mysql84 && hostDependentDefaults84[name]
+}
+
+// ServerVersion returns the flavor and version of the server a config was
+// read from, using its version and version_comment variables. version is
+// empty if the config has no version variable.
+func ServerVersion(cfg ConfigReader) (string, string) {
+	v, _ := cfg.Get("version")
+	version, _ := v.(string)
+	c, _ := cfg.Get("version_comment")
+	comment, _ := c.(string)
+
+	switch {
+	case strings.Contains(strings.ToLower(version), "mariadb"):
+		return "mariadb", version
+	case strings.Contains(strings.ToLower(comment), "percona"):
+		return "percona", version
+	}
+	return "mysql", version
+}
+
+// splitCatalogName splits a catalog name like percona-8.0.36-28 into the
+// flavor and the version.
+func splitCatalogName(name string) (string, string) {
+	if i := strings.IndexByte(name, '-'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// versionNumbers returns the major, minor and patch numbers at the start of a
+// version like 10.11.6-MariaDB-log. Missing numbers are not returned.
+func versionNumbers(version string) []int {
+	var numbers []int
+	for _, part := range strings.SplitN(version, ".", 3) {
+		if i := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+			part = part[:i]
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return len(a) - len(b)
+}
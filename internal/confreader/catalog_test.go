@@ -0,0 +1,117 @@
+package confreader
+
+import (
+	"strings"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestCatalogVersions(t *testing.T) {
+	names := CatalogVersions()
+	tu.Equals(t, names[:4], []string{"mariadb-10.6.17", "mariadb-10.11.7", "mariadb-11.4.2", "mysql-5.6.51"})
+
+	for _, name := range names {
+		cnf, err := NewCatalogReader(name)
+		tu.IsNil(t, err)
+		tu.Equals(t, cnf.Type(), "defaults")
+		maxConnections, _ := cnf.Get("max_connections")
+		tu.Equals(t, maxConnections, "151")
+	}
+
+	cnf, err := NewCatalogReader("mysql-8.0.36")
+	tu.IsNil(t, err)
+	charset, _ := cnf.Get("character_set_server")
+	tu.Equals(t, charset, "utf8mb4")
+
+	// The defaults that depend on the host are not the ones of the machine
+	// that generated the catalog.
+	cnf, err = NewCatalogReader("mysql-8.4.0")
+	tu.IsNil(t, err)
+	readThreads, _ := cnf.Get("innodb_read_io_threads")
+	tu.Equals(t, readThreads, Unknown("depends on the host"))
+
+	_, err = NewCatalogReader("mysql-9.9.9")
+	tu.NotNil(t, err)
+}
+
+func TestMarkHostDependent(t *testing.T) {
+	out := "mysqld  Ver 8.4.0 for Linux on x86_64 (MySQL Community Server - GPL)\n\n" +
+		"Variables (--variable-name=value)\n" +
+		"and boolean options {FALSE|TRUE}  Value (after reading options)\n" +
+		"--------------------------------- ----------------------------------------\n" +
+		"datadir                           /var/lib/mysql/\n" +
+		"innodb-read-io-threads            8\n" +
+		"max-connections                   151\n" +
+		"a-very-long-name-of-a-plugin-option-dir /opt\n" +
+		"tmpdir                            /tmp:\n" +
+		"                                  /var/tmp\n" +
+		"\n" +
+		"To see what values a running MySQL server is using, type\n"
+	want := "mysqld  Ver 8.4.0 for Linux on x86_64 (MySQL Community Server - GPL)\n\n" +
+		"Variables (--variable-name=value)\n" +
+		"and boolean options {FALSE|TRUE}  Value (after reading options)\n" +
+		"--------------------------------- ----------------------------------------\n" +
+		"datadir                           (host dependent)\n" +
+		"innodb-read-io-threads            (host dependent)\n" +
+		"max-connections                   151\n" +
+		"a-very-long-name-of-a-plugin-option-dir /opt\n" +
+		"tmpdir                            (host dependent)\n" +
+		"\n" +
+		"To see what values a running MySQL server is using, type\n"
+	tu.Equals(t, string(MarkHostDependent([]byte(out))), want)
+
+	// innodb_read_io_threads only depends on the host since 8.4.
+	out = strings.Replace(out, "Ver 8.4.0", "Ver 8.0.36", 1)
+	tu.Assert(t, strings.Contains(string(MarkHostDependent([]byte(out))), "innodb-read-io-threads            8\n"),
+		"innodb_read_io_threads doesn't depend on the host in 8.0")
+}
+
+func TestClosestCatalogVersion(t *testing.T) {
+	tests := []struct {
+		flavor  string
+		version string
+		want    string
+	}{
+		{"mysql", "8.0.36", "mysql-8.0.36"},
+		{"mysql", "8.0.12-log", "mysql-8.0.36"},
+		{"mysql", "8.4", "mysql-8.4.0"},
+		{"mysql", "5.6.10", "mysql-5.6.51"},
+		{"percona", "8.0.35-27", "percona-8.0.36-28"},
+		{"percona", "5.7.40-43", "percona-5.7.44-48"},
+		{"mariadb", "10.11.6-MariaDB-1:10.11.6+maria~ubu2204-log", "mariadb-10.11.7"},
+		{"mariadb", "10.6.12-MariaDB", "mariadb-10.6.17"},
+	}
+
+	for _, test := range tests {
+		name, err := ClosestCatalogVersion(test.flavor, test.version)
+		tu.IsNil(t, err)
+		tu.Equals(t, name, test.want)
+	}
+
+	_, err := ClosestCatalogVersion("mysql", "8.1.0")
+	tu.NotNil(t, err)
+	_, err = ClosestCatalogVersion("mariadb", "8.0.36")
+	tu.NotNil(t, err)
+	_, err = ClosestCatalogVersion("mysql", "")
+	tu.NotNil(t, err)
+}
+
+func TestServerVersion(t *testing.T) {
+	tests := []struct {
+		entries map[string]interface{}
+		flavor  string
+		version string
+	}{
+		{map[string]interface{}{"version": "8.0.36", "version_comment": "MySQL Community Server - GPL"}, "mysql", "8.0.36"},
+		{map[string]interface{}{"version": "8.0.36-28", "version_comment": "Percona Server (GPL), Release 28"}, "percona", "8.0.36-28"},
+		{map[string]interface{}{"version": "10.11.6-MariaDB-log"}, "mariadb", "10.11.6-MariaDB-log"},
+		{map[string]interface{}{"port": "3306"}, "mysql", ""},
+	}
+
+	for _, test := range tests {
+		flavor, version := ServerVersion(&Config{ConfigType: "mysql", EntriesMap: test.entries})
+		tu.Equals(t, flavor, test.flavor)
+		tu.Equals(t, version, test.version)
+	}
+}
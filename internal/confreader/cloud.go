@@ -0,0 +1,134 @@
+package confreader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type cloudSQLInstance struct {
+	Name     string
+	Settings *struct {
+		DatabaseFlags []struct {
+			Name  string
+			Value *string
+		}
+	}
+}
+
+type azureParameter struct {
+	Name   string
+	Value  *string
+	Source string
+}
+
+// NewCloudSQLReader reads the database flags of a Google Cloud SQL for MySQL
+// instance exported with gcloud sql instances describe --format=json. Flags
+// without a value, that Cloud SQL uses for boolean flags, are read as ON and
+// on/off values are upper cased like in SHOW VARIABLES.
+func NewCloudSQLReader(filename string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read Cloud SQL instance file")
+	}
+	defer f.Close()
+
+	return NewCloudSQLReaderFrom(f, filename)
+}
+
+// NewCloudSQLReaderFrom reads a Cloud SQL instance from r. filename is used in
+// origins.
+func NewCloudSQLReaderFrom(r io.Reader, filename string) (ConfigReader, error) {
+	var instance cloudSQLInstance
+	if err := json.NewDecoder(r).Decode(&instance); err != nil {
+		return nil, errors.Wrap(err, "invalid Cloud SQL instance file")
+	}
+	if instance.Settings == nil {
+		return nil, fmt.Errorf("invalid Cloud SQL instance file. There are no settings")
+	}
+
+	cnf := &Config{
+		ConfigType: "cloudsql",
+		EntriesMap: make(map[string]interface{}),
+		OriginsMap: make(map[string]Origin),
+	}
+	for _, flag := range instance.Settings.DatabaseFlags {
+		value := "ON"
+		if flag.Value != nil {
+			value = normalizeOnOff(*flag.Value)
+		}
+		cnf.EntriesMap[flag.Name] = value
+		cnf.Order = append(cnf.Order, flag.Name)
+		cnf.OriginsMap[flag.Name] = Origin{Source: "DATABASE_FLAG", Path: filename, Group: instance.Name}
+	}
+
+	return cnf, nil
+}
+
+// NewAzureReader reads the server parameters of an Azure Database for MySQL
+// flexible server exported with az mysql flexible-server parameter list. The
+// parameters having the system-default source are the defaults of Azure and
+// are skipped, and the source of the others, like user-override, is available
+// as their Origin. A single parameter, as exported by
+// az mysql flexible-server parameter show, can be read too.
+func NewAzureReader(filename string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read Azure parameters file")
+	}
+	defer f.Close()
+
+	return NewAzureReaderFrom(f, filename)
+}
+
+// NewAzureReaderFrom reads Azure server parameters from r. filename is used in
+// origins.
+func NewAzureReaderFrom(r io.Reader, filename string) (ConfigReader, error) {
+	var doc json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "invalid Azure parameters file")
+	}
+
+	var params []azureParameter
+	if err := json.Unmarshal(doc, &params); err != nil {
+		var param azureParameter
+		if err := json.Unmarshal(doc, &param); err != nil || param.Name == "" {
+			return nil, fmt.Errorf("invalid Azure parameters file. It must have a list of parameters")
+		}
+		params = append(params, param)
+	}
+
+	cnf := &Config{
+		ConfigType: "azure",
+		EntriesMap: make(map[string]interface{}),
+		OriginsMap: make(map[string]Origin),
+	}
+	for _, p := range params {
+		if p.Name == "" || p.Value == nil || p.Source == "system-default" {
+			continue
+		}
+		cnf.EntriesMap[p.Name] = normalizeOnOff(*p.Value)
+		cnf.Order = append(cnf.Order, p.Name)
+		cnf.OriginsMap[p.Name] = Origin{
+			Source: strings.ToUpper(strings.Replace(p.Source, "-", "_", -1)),
+			Path:   filename,
+		}
+	}
+
+	return cnf, nil
+}
+
+// normalizeOnOff upper cases on and off values, that Cloud SQL writes in lower
+// case, to compare them with SHOW VARIABLES.
+func normalizeOnOff(value string) string {
+	if strings.EqualFold(value, "on") || strings.EqualFold(value, "off") {
+		return strings.ToUpper(value)
+	}
+	return value
+}
@@ -0,0 +1,45 @@
+package confreader
+
+import (
+	"strings"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestCloudSQLReader(t *testing.T) {
+	cnf, err := NewCloudSQLReader("testdata/cloud/cloudsql-instance.json")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Type(), "cloudsql")
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"max_connections":    "500",
+		"slow_query_log":     "ON",
+		"skip_show_database": "ON",
+		"sql_mode":           "STRICT_TRANS_TABLES,NO_ZERO_DATE",
+	})
+	origin, ok := cnf.Origin("max_connections")
+	tu.Assert(t, ok, "max_connections should have an origin")
+	tu.Equals(t, origin, Origin{Source: "DATABASE_FLAG", Path: "testdata/cloud/cloudsql-instance.json", Group: "prod-mysql"})
+
+	_, err = NewCloudSQLReaderFrom(strings.NewReader(`{"name": "prod-mysql"}`), "instance.json")
+	tu.NotNil(t, err)
+}
+
+func TestAzureReader(t *testing.T) {
+	cnf, err := NewAzureReader("testdata/cloud/azure-parameters.json")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Type(), "azure")
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"max_connections": "800",
+	})
+	origin, ok := cnf.Origin("max_connections")
+	tu.Assert(t, ok, "max_connections should have an origin")
+	tu.Equals(t, origin, Origin{Source: "USER_OVERRIDE", Path: "testdata/cloud/azure-parameters.json"})
+
+	cnf, err = NewAzureReaderFrom(strings.NewReader(`{"name": "max_connections", "value": "100", "source": "user-override"}`), "parameter.json")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{"max_connections": "100"})
+
+	_, err = NewAzureReaderFrom(strings.NewReader(`{"Parameters": []}`), "parameters.json")
+	tu.NotNil(t, err)
+}
@@ -1,26 +1,149 @@
 package confreader
 
 import (
-	"fmt"
-
-	ini "gopkg.in/ini.v1"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-func NewCNFReader(filename string) (ConfigReader, error) {
+// DefaultGroups are the option groups read by every mysqld version.
+var DefaultGroups = []string{"mysqld", "server"}
+
+// ServerGroups returns the option groups mysqld reads for a server flavor
+// (mysql, percona or mariadb) and version, in the same order mysqld lists
+// them. If suffix is not empty, the groups for --defaults-group-suffix are
+// appended.
+func ServerGroups(flavor, version, suffix string) []string {
+	groups := append([]string{}, DefaultGroups...)
+	base := baseVersion(version)
+
+	if base != "" {
+		groups = append(groups, "mysqld-"+base)
+	}
+	switch flavor {
+	case "percona":
+		groups = append(groups, "percona-server")
+	case "mariadb":
+		groups = append(groups, "mariadb")
+		if base != "" {
+			groups = append(groups, "mariadb-"+base)
+		}
+		groups = append(groups, "mariadbd")
+		if base != "" {
+			groups = append(groups, "mariadbd-"+base)
+		}
+		groups = append(groups, "client-server", "galera")
+	}
+
+	if suffix != "" {
+		for _, group := range append([]string{}, groups...) {
+			groups = append(groups, group+suffix)
+		}
+	}
+	return groups
+}
+
+// baseVersion returns the major.minor part of a version like 8.0.36-28.
+func baseVersion(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	minor := parts[1]
+	if i := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = minor[:i]
+	}
+	if parts[0] == "" || minor == "" {
+		return ""
+	}
+	return parts[0] + "." + minor
+}
+
+// NewCNFReader reads a MySQL option file. The !include and !includedir
+// directives are followed recursively so the resulting config holds every
+// option mysqld would read.
+// Options are read from the given groups, or from DefaultGroups if none is
+// specified. Like mysqld, options are applied in the order they appear in the
+// files, so a later option wins no matter which group it belongs to.
+func NewCNFReader(filename string, groups ...string) (ConfigReader, error) {
 	filename = cleanFilename(filename)
-	cfg, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, filename)
-	if err != nil {
+	if len(groups) == 0 {
+		groups = DefaultGroups
+	}
+
+	p := newOptionFileParser(groups)
+	if err := p.parseFile(filename); err != nil {
 		return nil, err
 	}
-	if cfg == nil {
-		return nil, fmt.Errorf("Invalid file: %s", filename)
+
+	return newOptionsConfig("cnf", p.options), nil
+}
+
+// NewCNFReaderFrom reads an option file from r. name is used to resolve
+// relative include paths and in error messages. See NewCNFReader.
+func NewCNFReaderFrom(r io.Reader, name string, groups ...string) (ConfigReader, error) {
+	if len(groups) == 0 {
+		groups = DefaultGroups
+	}
+
+	p := newOptionFileParser(groups)
+	if err := p.parse(r, name); err != nil {
+		return nil, err
 	}
 
-	cnf := &Config{ConfigType: "cnf", EntriesMap: make(map[string]interface{})}
+	return newOptionsConfig("cnf", p.options), nil
+}
+
+// DefaultFiles returns the option files mysqld reads when no --defaults-file
+// is given, in the order they are read:
+//
+//	/etc/my.cnf
+//	/etc/mysql/my.cnf
+//	SYSCONFDIR/my.cnf
+//	$MYSQL_HOME/my.cnf
+//	--defaults-extra-file
+//	~/.my.cnf
+//
+// Empty arguments are skipped.
+func DefaultFiles(sysconfdir, mysqlHome, extraFile, homeDir string) []string {
+	files := []string{"/etc/my.cnf", "/etc/mysql/my.cnf"}
+	if sysconfdir != "" {
+		files = append(files, filepath.Join(sysconfdir, "my.cnf"))
+	}
+	if mysqlHome != "" {
+		files = append(files, filepath.Join(mysqlHome, "my.cnf"))
+	}
+	if extraFile != "" {
+		files = append(files, extraFile)
+	}
+	if homeDir != "" {
+		files = append(files, filepath.Join(homeDir, ".my.cnf"))
+	}
+	return files
+}
+
+// NewDefaultFilesReader reads the given option files (see DefaultFiles) as
+// found under the root directory, so the layout of another host can be
+// examined from a copy of its files. Missing files are skipped. Options in a
+// later file override the ones in the previous files.
+func NewDefaultFilesReader(root string, files []string, groups ...string) (ConfigReader, error) {
+	root = cleanFilename(root)
+	if len(groups) == 0 {
+		groups = DefaultGroups
+	}
 
-	for _, key := range cfg.Section("mysqld").Keys() {
-		cnf.EntriesMap[key.Name()] = key.Value()
+	p := newOptionFileParser(groups)
+	p.root = root
+	for _, file := range files {
+		filename := filepath.Join(root, file)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			continue
+		}
+		if err := p.parseFile(filename); err != nil {
+			return nil, err
+		}
 	}
 
-	return cnf, nil
+	return newOptionsConfig("cnf", p.options), nil
 }
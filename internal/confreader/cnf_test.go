@@ -0,0 +1,171 @@
+package confreader
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestCNFReaderIncludes(t *testing.T) {
+	cnf, err := NewCNFReader("testdata/includes/my.cnf")
+	tu.IsNil(t, err)
+
+	want := map[string]interface{}{
+		"port":                    "3306",
+		"max_connections":         "300",
+		"sort_buffer_size":        "1M",
+		"innodb_buffer_pool_size": "512M",
+		"max_allowed_packet":      "64M",
+	}
+	tu.Equals(t, cnf.Entries(), want)
+}
+
+func TestCNFReaderIncludeCycle(t *testing.T) {
+	_, err := NewCNFReader("testdata/includes/cycle/a.cnf")
+	tu.NotNil(t, err)
+}
+
+func TestCNFReaderGroups(t *testing.T) {
+	cnf, err := NewCNFReader("testdata/groups/my.cnf")
+	tu.IsNil(t, err)
+	want := map[string]interface{}{
+		"max_connections":  "300",
+		"sort_buffer_size": "2M",
+		"replicate-do-db":  []string{"db1", "db2", "db3"},
+	}
+	tu.Equals(t, cnf.Entries(), want)
+
+	cnf, err = NewCNFReader("testdata/groups/my.cnf", ServerGroups("mysql", "8.0.36", "_replica")...)
+	tu.IsNil(t, err)
+	want = map[string]interface{}{
+		"max_connections":         "300",
+		"sort_buffer_size":        "2M",
+		"innodb_buffer_pool_size": "1G",
+		"server_id":               "2",
+		"replicate-do-db":         []string{"db1", "db2", "db3"},
+	}
+	tu.Equals(t, cnf.Entries(), want)
+
+	cnf, err = NewCNFReader("testdata/groups/my.cnf", ServerGroups("mariadb", "10.6.12-MariaDB", "")...)
+	tu.IsNil(t, err)
+	want = map[string]interface{}{
+		"max_connections":            "300",
+		"sort_buffer_size":           "2M",
+		"aria_pagecache_buffer_size": "64M",
+		"replicate-do-db":            []string{"db1", "db2", "db3"},
+	}
+	tu.Equals(t, cnf.Entries(), want)
+}
+
+func TestServerGroups(t *testing.T) {
+	tu.Equals(t, ServerGroups("mysql", "", ""), []string{"mysqld", "server"})
+	tu.Equals(t, ServerGroups("percona", "5.7.44-48", "-a"), []string{
+		"mysqld", "server", "mysqld-5.7", "percona-server",
+		"mysqld-a", "server-a", "mysqld-5.7-a", "percona-server-a",
+	})
+}
+
+func TestCNFReaderQuoting(t *testing.T) {
+	cnf, err := NewCNFReader("testdata/quoting/my.cnf")
+	tu.IsNil(t, err)
+	want := map[string]interface{}{
+		"datadir":              "/var/lib/my sql",
+		"tmpdir":               "/tmp/a#b",
+		"init_connect":         "SET NAMES utf8mb4; SET @a = 'x # y'",
+		"log_error":            "/var/log/mysql/error.log",
+		"ssl_cipher":           "ECDHE RSA",
+		"lc_messages_dir":      `C:\Program Files\MySQL\share`,
+		"secure_file_priv":     "",
+		"character_set_server": "utf8mb4",
+		"skip-name-resolve":    "ON",
+		"max_connections":      "500",
+	}
+	tu.Equals(t, cnf.Entries(), want)
+}
+
+func TestParseOptionLine(t *testing.T) {
+	tests := []struct {
+		line, name, value string
+	}{
+		{`key = value # comment`, "key", "value"},
+		{`key="a \"quoted\" value"`, "key", `a "quoted" value`},
+		{`key = 'it''s'`, "key", "it''s"},
+		{`key = a\tb\nc\\d`, "key", "a\tb\nc\\d"},
+		{`key = "unbalanced`, "key", `"unbalanced`},
+		{`bare-option # comment`, "bare-option", "ON"},
+	}
+	for _, test := range tests {
+		name, value := parseOptionLine(test.line)
+		tu.Equals(t, name, test.name)
+		tu.Equals(t, value, test.value)
+	}
+}
+
+func TestCNFReaderErrors(t *testing.T) {
+	_, err := NewCNFReader("testdata/quoting/missing.cnf")
+	tu.NotNil(t, err)
+
+	p := newOptionFileParser(DefaultGroups)
+	tu.NotNil(t, p.parse(strings.NewReader("key = value\n"), "test.cnf"))
+	tu.NotNil(t, p.parse(strings.NewReader("[mysqld\nkey = value\n"), "test.cnf"))
+}
+
+func TestDefaultFilesReader(t *testing.T) {
+	files := DefaultFiles("/usr/local/mysql/etc", "/var/lib/mysql", "/opt/extra.cnf", "/home/mysql")
+	tu.Equals(t, files, []string{
+		"/etc/my.cnf",
+		"/etc/mysql/my.cnf",
+		"/usr/local/mysql/etc/my.cnf",
+		"/var/lib/mysql/my.cnf",
+		"/opt/extra.cnf",
+		"/home/mysql/.my.cnf",
+	})
+
+	cnf, err := NewDefaultFilesReader("testdata/searchpath", files)
+	tu.IsNil(t, err)
+	want := map[string]interface{}{
+		"max_connections":  "300",
+		"port":             "3307",
+		"sort_buffer_size": "1M",
+		"tmpdir":           "/tmp/mysql",
+	}
+	tu.Equals(t, cnf.Entries(), want)
+}
+
+func TestCNFReaderProvenance(t *testing.T) {
+	cnf, err := NewCNFReader("testdata/includes/my.cnf")
+	tu.IsNil(t, err)
+
+	base, err := filepath.Abs("testdata/includes")
+	tu.IsNil(t, err)
+
+	want := []Origin{
+		{Path: filepath.Join(base, "my.cnf"), Line: 3, Group: "mysqld", Overridden: true},
+		{Path: filepath.Join(base, "conf.d/a.cnf"), Line: 2, Group: "mysqld", Overridden: true},
+		{Path: filepath.Join(base, "conf.d/b.cnf"), Line: 2, Group: "mysqld"},
+	}
+	tu.Equals(t, cnf.Provenance("max_connections"), want)
+
+	origin, ok := cnf.Origin("max_connections")
+	tu.Assert(t, ok, "max_connections should have an origin")
+	tu.Equals(t, origin, want[2])
+	tu.Equals(t, origin.String(), filepath.Join(base, "conf.d/b.cnf")+":2 [mysqld]")
+}
+
+func TestCNFReaderSpellings(t *testing.T) {
+	p := newOptionFileParser(DefaultGroups)
+	cnf := "[mysqld]\nmax_connections = 100\nmax-connections = 200\nlog-bin = binlog\nskip-log-bin\n"
+	tu.IsNil(t, p.parse(strings.NewReader(cnf), "my.cnf"))
+
+	got := newOptionsConfig("cnf", p.options)
+	tu.Equals(t, got.Entries(), map[string]interface{}{
+		"max-connections": "200",
+		"skip-log-bin":    "ON",
+	})
+	tu.Equals(t, got.Provenance("max-connections"), []Origin{
+		{Path: "my.cnf", Line: 2, Group: "mysqld", Overridden: true},
+		{Path: "my.cnf", Line: 3, Group: "mysqld"},
+	})
+}
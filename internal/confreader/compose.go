@@ -0,0 +1,260 @@
+package confreader
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// composeVarRe matches the variables docker compose interpolates: $$, $VAR,
+// ${VAR} and ${VAR:-default}, ${VAR-default}, ${VAR:?error} or ${VAR?error}.
+var composeVarRe = regexp.MustCompile(`\$(?:\$|([A-Za-z_][A-Za-z0-9_]*)|\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-?])([^}]*))?\})`)
+
+// composeEnvPrefixes are the prefixes of the environment variables the MySQL,
+// Percona Server and MariaDB images use to set up the server.
+var composeEnvPrefixes = []string{"MYSQL_", "MARIADB_"}
+
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Command     interface{} `yaml:"command"`
+	Entrypoint  interface{} `yaml:"entrypoint"`
+	Environment interface{} `yaml:"environment"`
+}
+
+// NewComposeReader reads the mysqld options set by a docker compose service:
+// the options in its command and entrypoint. The MYSQL_* and MARIADB_*
+// environment variables the server images use, like MYSQL_ALLOW_EMPTY_PASSWORD,
+// are not mysqld options and are kept apart in the Environment of the config,
+// with the values of password variables masked. service can be empty if the
+// file has only one service.
+//
+// Variables like ${MYSQL_PORT:-3306} are interpolated from the environment and
+// from the .env file next to the compose file, like docker compose does.
+func NewComposeReader(filename, service string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read compose file")
+	}
+
+	var doc composeFile
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "invalid compose file")
+	}
+
+	if service == "" && len(doc.Services) == 1 {
+		for name := range doc.Services {
+			service = name
+		}
+	}
+	svc, ok := doc.Services[service]
+	if !ok {
+		var names []string
+		for name := range doc.Services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("choose one of the services in %s: %s", filename, strings.Join(names, ", "))
+	}
+
+	env, err := readDotEnv(filepath.Join(filepath.Dir(filename), ".env"))
+	if err != nil {
+		return nil, err
+	}
+	lookup := func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := env[name]
+		return value, ok
+	}
+
+	var args []string
+	for _, field := range []interface{}{svc.Entrypoint, svc.Command} {
+		words, err := composeWords(field, lookup)
+		if err != nil {
+			return nil, errors.Wrapf(err, "service %s", service)
+		}
+		args = append(args, words...)
+	}
+	// Skip the programs, like docker-entrypoint.sh mysqld, before the options.
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+
+	options, err := parseCommandLine(args, filename, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "service %s", service)
+	}
+	for i := range options {
+		options[i].Group, options[i].Source = service, "COMMAND_LINE"
+	}
+
+	environment, err := composeEnvironment(svc.Environment, lookup)
+	if err != nil {
+		return nil, errors.Wrapf(err, "service %s", service)
+	}
+
+	cnf := newOptionsConfig("args", options)
+	cnf.Environment = environment
+	return cnf, nil
+}
+
+// composeWords returns the words in a command or entrypoint, that can be
+// either a string split like a shell does or a list of words.
+func composeWords(field interface{}, lookup func(string) (string, bool)) ([]string, error) {
+	switch v := field.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		s, err := interpolate(v, lookup)
+		if err != nil {
+			return nil, err
+		}
+		return splitWords(s)
+	case []interface{}:
+		var words []string
+		for _, item := range v {
+			s, err := interpolate(fmt.Sprint(item), lookup)
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, s)
+		}
+		return words, nil
+	}
+	return nil, fmt.Errorf("invalid command %v", field)
+}
+
+// composeEnvironment returns the server image variables in an environment,
+// that can be either a list of NAME=value or a map.
+func composeEnvironment(field interface{}, lookup func(string) (string, bool)) (map[string]string, error) {
+	vars := make(map[string]string)
+	switch v := field.(type) {
+	case nil:
+	case []interface{}:
+		for _, item := range v {
+			s := fmt.Sprint(item)
+			if eq := strings.IndexByte(s, '='); eq > 0 {
+				vars[s[:eq]] = s[eq+1:]
+			} else if value, ok := lookup(s); ok {
+				// A variable without a value is passed from the environment.
+				vars[s] = value
+			}
+		}
+	case map[interface{}]interface{}:
+		for name, value := range v {
+			if value == nil {
+				value = ""
+			}
+			vars[fmt.Sprint(name)] = fmt.Sprint(value)
+		}
+	default:
+		return nil, fmt.Errorf("invalid environment %v", field)
+	}
+
+	environment := make(map[string]string)
+	for name, value := range vars {
+		if !hasAnyPrefix(name, composeEnvPrefixes) {
+			continue
+		}
+		value, err := interpolate(value, lookup)
+		if err != nil {
+			return nil, err
+		}
+		if isSecretVariable(name) && value != "" {
+			value = "********"
+		}
+		environment[name] = value
+	}
+	return environment, nil
+}
+
+// interpolate replaces the variables in s like docker compose does.
+func interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	var err error
+	result := composeVarRe.ReplaceAllStringFunc(s, func(ref string) string {
+		m := composeVarRe.FindStringSubmatch(ref)
+		if ref == "$$" {
+			return "$"
+		}
+		name, op, arg := m[1]+m[2], m[3], m[4]
+		value, ok := lookup(name)
+		unset := !ok || (strings.HasPrefix(op, ":") && value == "")
+
+		switch strings.TrimPrefix(op, ":") {
+		case "-":
+			if unset {
+				return arg
+			}
+		case "?":
+			if unset {
+				err = fmt.Errorf("required variable %s is missing a value: %s", name, arg)
+			}
+		}
+		return value
+	})
+	return result, err
+}
+
+// readDotEnv reads the NAME=value lines of a docker compose .env file. A
+// missing file has no variables.
+func readDotEnv(filename string) (map[string]string, error) {
+	env := make(map[string]string)
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return env, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if eq := strings.IndexByte(line, '='); eq > 0 {
+			value := strings.TrimSpace(line[eq+1:])
+			if len(value) > 1 && (value[0] == '\'' || value[0] == '"') && value[0] == value[len(value)-1] {
+				value = value[1 : len(value)-1]
+			}
+			env[strings.TrimSpace(line[:eq])] = value
+		}
+	}
+	return env, s.Err()
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecretVariable returns true for the variables having a password, like
+// MYSQL_ROOT_PASSWORD. Settings like MYSQL_ALLOW_EMPTY_PASSWORD are not secret.
+func isSecretVariable(name string) bool {
+	for _, prefix := range composeEnvPrefixes {
+		switch strings.TrimPrefix(name, prefix) {
+		case "PASSWORD", "ROOT_PASSWORD", "PASSWORD_HASH", "ROOT_PASSWORD_HASH":
+			return true
+		}
+	}
+	return false
+}
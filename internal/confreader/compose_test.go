@@ -0,0 +1,63 @@
+package confreader
+
+import (
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestComposeReader(t *testing.T) {
+	cnf, err := NewComposeReader("testdata/compose/docker-compose.yml", "mysql")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"performance-schema": "ON",
+		"secure-file-priv":   "",
+		"max-connections":    "300",
+	})
+	tu.Equals(t, cnf.(*Config).Environment, map[string]string{
+		"MYSQL_ALLOW_EMPTY_PASSWORD": "yes",
+		"MYSQL_ROOT_PASSWORD":        "********",
+		"MYSQL_DATABASE":             "test",
+	})
+
+	origin, ok := cnf.Origin("max-connections")
+	tu.Assert(t, ok, "max-connections should have an origin")
+	tu.Equals(t, origin, Origin{Source: "COMMAND_LINE", Path: "testdata/compose/docker-compose.yml", Group: "mysql"})
+
+	cnf, err = NewComposeReader("testdata/compose/docker-compose.yml", "mariadb")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"innodb-buffer-pool-size": "1G",
+		"character-set-server":    "utf8mb4",
+		"sql-mode":                "${SQL_MODE}",
+	})
+	tu.Equals(t, cnf.(*Config).Environment, map[string]string{
+		"MARIADB_RANDOM_ROOT_PASSWORD": "yes",
+		"MARIADB_USER":                 "app",
+	})
+
+	_, err = NewComposeReader("testdata/compose/docker-compose.yml", "")
+	tu.NotNil(t, err)
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{"SET": "1", "EMPTY": ""}[name]
+		return value, ok
+	}
+	tests := map[string]string{
+		"$SET ${SET}":             "1 1",
+		"${UNSET:-a} ${EMPTY:-b}": "a b",
+		"${UNSET-a} ${EMPTY-b}":   "a ",
+		"$$SET ${UNSET}":          "$SET ",
+		"${SET:?must be set}":     "1",
+	}
+	for s, want := range tests {
+		got, err := interpolate(s, lookup)
+		tu.IsNil(t, err)
+		tu.Equals(t, got, want)
+	}
+
+	_, err := interpolate("${EMPTY:?must be set}", lookup)
+	tu.NotNil(t, err)
+}
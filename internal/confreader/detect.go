@@ -0,0 +1,135 @@
+package confreader
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// composeServicesRe matches the top level services key of a compose file.
+var composeServicesRe = regexp.MustCompile(`(?m)^services:\s*$`)
+
+// azureParameterType is the type of the Azure Database for MySQL parameters.
+const azureParameterType = "Microsoft.DBforMySQL/flexibleServers/configurations"
+
+// terraformResourceRe matches the Terraform resources defining RDS parameter
+// groups.
+var terraformResourceRe = regexp.MustCompile(`(?m)^\s*resource\s+"(aws_db_parameter_group|aws_rds_cluster_parameter_group)"`)
+
+// k8sKindRe matches the kind of a Kubernetes resource.
+var k8sKindRe = regexp.MustCompile(`(?m)^kind:\s*\S+`)
+
+// DetectFormat guesses the format of the contents of a config source. It
+// returns one of:
+//
+//	auto            mysqld-auto.cnf persisted variables
+//	snapshot        a config saved as JSON
+//	rds             AWS RDS parameter group
+//	cloudsql        Google Cloud SQL instance
+//	azure           Azure Database for MySQL parameters
+//	defaults        mysqld --verbose --help output
+//	print-defaults  my_print_defaults or mysqld --print-defaults output
+//	cmdline         mysqld arguments saved from /proc/<pid>/cmdline
+//	systemd         systemd unit starting mysqld
+//	compose         docker compose file
+//	k8s             Kubernetes manifest
+//	terraform       Terraform file with RDS parameter groups
+//	variables       SHOW VARIABLES output captured with mysql or mysqladmin
+//	cnf             an option file
+//
+// cnf is returned when no other format matches.
+func DetectFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var doc map[string]json.RawMessage
+		if json.Unmarshal(trimmed, &doc) == nil {
+			if _, ok := doc[persistedServerSection]; ok {
+				return "auto"
+			}
+			if _, ok := doc["EntriesMap"]; ok {
+				return "snapshot"
+			}
+			if _, ok := doc["Parameters"]; ok {
+				return "rds"
+			}
+			if bytes.Contains(doc["settings"], []byte(`"databaseFlags"`)) {
+				return "cloudsql"
+			}
+		}
+	}
+
+	if bytes.Contains(trimmed, []byte(azureParameterType)) {
+		return "azure"
+	}
+
+	if bytes.Contains(data, []byte("Variables (--variable-name=value)")) {
+		return "defaults"
+	}
+
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "cmdline"
+	}
+
+	if bytes.Contains(data, []byte("[Service]")) && bytes.Contains(data, []byte("ExecStart")) {
+		return "systemd"
+	}
+
+	if terraformResourceRe.Match(data) {
+		return "terraform"
+	}
+
+	if k8sKindRe.Match(data) && bytes.Contains(data, []byte("apiVersion:")) {
+		return "k8s"
+	}
+
+	if composeServicesRe.Match(data) {
+		return "compose"
+	}
+
+	if isVariables(trimmed) {
+		return "variables"
+	}
+
+	if isPrintDefaults(trimmed) {
+		return "print-defaults"
+	}
+
+	return "cnf"
+}
+
+// isVariables returns true if data is a boxed table having a Variable_name
+// column or if all the lines are tab separated variables.
+func isVariables(data []byte) bool {
+	if bytes.Contains(data, []byte("| Variable_name")) {
+		return true
+	}
+	if len(data) == 0 {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !variablesLineRe.MatchString(line) && !strings.HasPrefix(line, "TS ") {
+			return false
+		}
+	}
+	return true
+}
+
+// isPrintDefaults returns true if data has only --name=value arguments, one
+// per line or after the mysqld --print-defaults header.
+func isPrintDefaults(data []byte) bool {
+	if bytes.Contains(data, []byte(printDefaultsHeader)) {
+		return true
+	}
+	if len(data) == 0 {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
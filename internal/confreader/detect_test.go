@@ -0,0 +1,38 @@
+package confreader
+
+import (
+	"io/ioutil"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"testdata/mysqld-auto.cnf":                 "auto",
+		"testdata/want_defaults.json":              "snapshot",
+		"testdata/defaults.txt":                    "defaults",
+		"testdata/defaults/mariadb-10.11.txt":      "defaults",
+		"testdata/defaults/mysql-8.0-warnings.txt": "defaults",
+		"testdata/groups/my.cnf":                   "cnf",
+
+		"testdata/printdefaults/my_print_defaults.txt": "print-defaults",
+		"testdata/printdefaults/print-defaults.txt":    "print-defaults",
+		"testdata/cmdline":                             "cmdline",
+		"testdata/systemd/mysqld.service":              "systemd",
+		"testdata/compose/docker-compose.yml":          "compose",
+		"testdata/manifests/cluster.yaml":              "k8s",
+		"testdata/cloud/rds-parameters.json":           "rds",
+		"testdata/terraform/rds.tf":                    "terraform",
+		"testdata/cloud/cloudsql-instance.json":        "cloudsql",
+		"testdata/cloud/azure-parameters.json":         "azure",
+		"testdata/variables/mysqladmin.txt":            "variables",
+		"testdata/variables/batch.txt":                 "variables",
+		"testdata/variables/batch-no-header.txt":       "variables",
+	}
+	for filename, want := range tests {
+		data, err := ioutil.ReadFile(filename)
+		tu.IsNil(t, err)
+		tu.Equals(t, DetectFormat(data), want)
+	}
+}
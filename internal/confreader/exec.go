@@ -0,0 +1,187 @@
+package confreader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultExecTimeout is the time mysqld can run to print its defaults when no
+// timeout is given.
+const DefaultExecTimeout = 30 * time.Second
+
+// cacheNameRe matches the characters of a version that are not kept in the
+// name of a cache file.
+var cacheNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ExecOptions controls how a mysqld binary is run to get its defaults.
+type ExecOptions struct {
+	// Timeout is the time mysqld can run. Zero means DefaultExecTimeout.
+	Timeout time.Duration
+	// CacheDir is where the output of mysqld is saved, so it only runs once
+	// for every binary. Cache files are named after the version and the
+	// checksum of the binary.
+	CacheDir string
+}
+
+// NewExecDefaultsReader reads the defaults of a mysqld binary by running it
+// with --no-defaults --verbose --help. See GenerateDefaults.
+func NewExecDefaultsReader(binary string, opts ExecOptions) (ConfigReader, error) {
+	out, err := GenerateDefaults(binary, opts)
+	if err != nil {
+		return nil, err
+	}
+	return parseFile(bytes.NewReader(out), binary)
+}
+
+// GenerateDefaults returns the output of mysqld --no-defaults --verbose --help
+// for a mysqld binary, which can be a path or a command in the PATH. mysqld
+// runs in an empty temporary directory, that is also its HOME and MYSQL_HOME,
+// so no option file or local setting changes the defaults, and it is killed
+// if it doesn't finish within the timeout.
+func GenerateDefaults(binary string, opts ExecOptions) ([]byte, error) {
+	path, err := exec.LookPath(cleanFilename(binary))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot find mysqld")
+	}
+
+	var cacheFile string
+	if opts.CacheDir != "" {
+		cacheFile, err = defaultsCacheFile(path, opts)
+		if err != nil {
+			return nil, err
+		}
+		if out, err := ioutil.ReadFile(cacheFile); err == nil {
+			return out, nil
+		}
+	}
+
+	out, err := runMySQLd(path, opts.Timeout, "--no-defaults", "--verbose", "--help")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(out, []byte("Variables (--variable-name=value)")) {
+		return nil, fmt.Errorf("there are no defaults in the output of %s --verbose --help", path)
+	}
+
+	if cacheFile != "" {
+		if err := writeFileAtomic(cacheFile, out); err != nil {
+			return nil, errors.Wrap(err, "cannot save the defaults in the cache")
+		}
+	}
+	return out, nil
+}
+
+// defaultsCacheFile returns the cache file for a mysqld binary, like
+// 8.0.36-1a2b3c4d5e6f.txt. The version is read from mysqld --version.
+func defaultsCacheFile(path string, opts ExecOptions) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot read mysqld")
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrap(err, "cannot read mysqld")
+	}
+	checksum := hex.EncodeToString(h.Sum(nil))
+
+	out, err := runMySQLd(path, opts.Timeout, "--no-defaults", "--version")
+	if err != nil {
+		return "", err
+	}
+	m := helpVersionRe.FindSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("cannot find the version in the output of %s --version: %q", path, strings.TrimSpace(string(out)))
+	}
+	version := cacheNameRe.ReplaceAllString(string(m[1]), "_")
+
+	return filepath.Join(cleanFilename(opts.CacheDir), version+"-"+checksum[:12]+".txt"), nil
+}
+
+// runMySQLd runs mysqld in an empty temporary directory and returns what it
+// writes to the standard output. mysqld and the processes it starts are
+// killed after the timeout. The output is written to files instead of pipes,
+// so processes left behind by a wrapper script can't keep runMySQLd waiting
+// for the output to be closed.
+func runMySQLd(path string, timeout time.Duration, args ...string) ([]byte, error) {
+	if timeout == 0 {
+		timeout = DefaultExecTimeout
+	}
+	tmpDir, err := ioutil.TempDir("", "pt-mysql-config-diff")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir := filepath.Join(tmpDir, "home")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return nil, err
+	}
+	stdout, err := os.Create(filepath.Join(tmpDir, "stdout"))
+	if err != nil {
+		return nil, err
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(filepath.Join(tmpDir, "stderr"))
+	if err != nil {
+		return nil, err
+	}
+	defer stderr.Close()
+
+	cmd := exec.Command(path, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HOME="+dir, "MYSQL_HOME="+dir)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// mysqld can be a wrapper script, whose children must be killed too.
+	newProcessGroup(cmd)
+
+	command := strings.TrimSpace(path + " " + strings.Join(args, " "))
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, command)
+	}
+	timer := time.AfterFunc(timeout, func() { killProcessGroup(cmd) })
+	err = cmd.Wait()
+	if !timer.Stop() {
+		return nil, fmt.Errorf("%s didn't finish in %s", command, timeout)
+	}
+	if err != nil {
+		msg, _ := ioutil.ReadFile(stderr.Name())
+		return nil, errors.Wrapf(err, "%s: %s", command, strings.TrimSpace(string(msg)))
+	}
+	return ioutil.ReadFile(stdout.Name())
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it, so other processes never read a partial file.
+func writeFileAtomic(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(filename), ".defaults")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
@@ -0,0 +1,15 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package confreader
+
+import "os/exec"
+
+// newProcessGroup does nothing where there are no process groups.
+func newProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills a command. The processes it started can keep
+// running, but runMySQLd doesn't wait for them.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,91 @@
+package confreader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+// fakeMySQLd writes a shell script behaving like mysqld --version and
+// mysqld --verbose --help. Every call is appended to the calls file.
+func fakeMySQLd(t *testing.T, dir, help string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake mysqld is a shell script")
+	}
+	help, err := filepath.Abs(help)
+	tu.IsNil(t, err)
+
+	binary := filepath.Join(dir, "mysqld")
+	script := `#!/bin/sh
+echo "$(pwd) $*" >> ` + filepath.Join(dir, "calls") + `
+case "$*" in
+*--version*) echo "/usr/sbin/mysqld  Ver 8.0.36 for Linux on x86_64 (MySQL Community Server - GPL)" ;;
+*--help*) cat ` + help + ` ;;
+esac
+`
+	tu.IsNil(t, ioutil.WriteFile(binary, []byte(script), 0755))
+	return binary
+}
+
+func TestExecDefaultsReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confdiff-exec")
+	tu.IsNil(t, err)
+	defer os.RemoveAll(dir)
+	binary := fakeMySQLd(t, dir, "testdata/defaults/mysql-8.0-warnings.txt")
+	cacheDir := filepath.Join(dir, "cache")
+
+	for i := 0; i < 2; i++ {
+		cnf, err := NewExecDefaultsReader(binary, ExecOptions{CacheDir: cacheDir})
+		tu.IsNil(t, err)
+		tu.Equals(t, cnf.(*Config).Version, "8.0.36")
+		maxConnections, _ := cnf.Get("max_connections")
+		tu.Equals(t, maxConnections, "151")
+	}
+
+	// The second time, the output is read from the cache.
+	data, err := ioutil.ReadFile(filepath.Join(dir, "calls"))
+	tu.IsNil(t, err)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	tu.Equals(t, len(calls), 3)
+	for i, want := range []string{"--no-defaults --version", "--no-defaults --verbose --help", "--no-defaults --version"} {
+		tu.Assert(t, strings.HasSuffix(calls[i], " "+want), "call %d is %q", i, calls[i])
+		tu.Assert(t, !strings.HasPrefix(calls[i], dir), "mysqld should run in a temporary directory: %q", calls[i])
+	}
+
+	cached, err := filepath.Glob(filepath.Join(cacheDir, "8.0.36-*.txt"))
+	tu.IsNil(t, err)
+	tu.Equals(t, len(cached), 1)
+}
+
+func TestExecDefaultsReaderErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confdiff-exec")
+	tu.IsNil(t, err)
+	defer os.RemoveAll(dir)
+
+	binary := fakeMySQLd(t, dir, "testdata/groups/my.cnf")
+	_, err = NewExecDefaultsReader(binary, ExecOptions{})
+	tu.NotNil(t, err)
+
+	// The processes started by a wrapper script are killed too.
+	slow := filepath.Join(dir, "slow-mysqld")
+	late := filepath.Join(dir, "late")
+	script := fmt.Sprintf("#!/bin/sh\n(sleep 1; touch %s) &\nsleep 5\necho done\n", late)
+	tu.IsNil(t, ioutil.WriteFile(slow, []byte(script), 0755))
+	start := time.Now()
+	_, err = NewExecDefaultsReader(slow, ExecOptions{Timeout: 100 * time.Millisecond})
+	tu.NotNil(t, err)
+	tu.Assert(t, time.Since(start) < 4*time.Second, "mysqld should be killed after the timeout")
+	time.Sleep(1500 * time.Millisecond)
+	_, err = os.Stat(late)
+	tu.Assert(t, os.IsNotExist(err), "the children of mysqld should be killed after the timeout")
+
+	_, err = NewExecDefaultsReader(filepath.Join(dir, "no-such-mysqld"), ExecOptions{})
+	tu.NotNil(t, err)
+}
@@ -0,0 +1,112 @@
+package confreader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NewGitCNFReader reads an option file as it was at a revision of the git
+// repository in the repo directory, without checking it out. !include and
+// !includedir directives are followed within the same revision.
+//
+// Like in git rev:path expressions, filename is relative to the root of the
+// repository unless it starts with ./ or ../, which makes it relative to the
+// repo directory. Absolute include paths, like /etc/mysql/conf.d/, are read
+// from the root of the repository, as etc/mysql/conf.d/.
+func NewGitCNFReader(repo, rev, filename string, groups ...string) (ConfigReader, error) {
+	if len(groups) == 0 {
+		groups = DefaultGroups
+	}
+	if repo == "" {
+		repo = "."
+	}
+
+	top, err := runGit(repo, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(filename, "./") || strings.HasPrefix(filename, "../") {
+		prefix, err := runGit(repo, "rev-parse", "--show-prefix")
+		if err != nil {
+			return nil, err
+		}
+		filename = path.Join(string(bytes.TrimSpace(prefix)), filename)
+	}
+	if _, err := runGit(repo, "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git revision %q", rev)
+	}
+
+	p := newOptionFileParser(groups)
+	p.fs = &gitFileSystem{dir: string(bytes.TrimSpace(top)), rev: rev}
+	if err := p.parseFile(filename); err != nil {
+		return nil, err
+	}
+
+	// Origins show the revision of every file, like v1.2:etc/my.cnf.
+	for i := range p.options {
+		p.options[i].File = rev + ":" + p.options[i].File
+	}
+
+	return newOptionsConfig("cnf", p.options), nil
+}
+
+// gitFileSystem reads the files of a git revision. File names are relative to
+// the root of the repository.
+type gitFileSystem struct {
+	dir string
+	rev string
+}
+
+func (g *gitFileSystem) abs(name string) (string, error) {
+	return path.Clean(strings.TrimPrefix(name, "/")), nil
+}
+
+func (g *gitFileSystem) open(name string) (io.ReadCloser, error) {
+	data, err := runGit(g.dir, "cat-file", "blob", g.rev+":"+name)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (g *gitFileSystem) readDir(name string) ([]string, error) {
+	name, _ = g.abs(name)
+	out, err := runGit(g.dir, "ls-tree", "-z", g.rev+":"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every entry is <mode> SP <type> SP <object> TAB <name> NUL.
+	var names []string
+	for _, entry := range strings.Split(strings.TrimRight(string(out), "\x00"), "\x00") {
+		tab := strings.IndexByte(entry, '\t')
+		if tab < 0 {
+			continue
+		}
+		if fields := strings.Fields(entry[:tab]); len(fields) == 3 && fields[1] == "blob" {
+			names = append(names, entry[tab+1:])
+		}
+	}
+	return names, nil
+}
+
+// runGit runs a git command in dir and returns its output.
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,65 @@
+package confreader
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestGitCNFReader(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo, err := ioutil.TempDir("", "confdiff-git")
+	tu.IsNil(t, err)
+	defer os.RemoveAll(repo)
+
+	git := func(args ...string) {
+		_, err := runGit(repo, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		tu.IsNil(t, err)
+	}
+	write := func(name, data string) {
+		filename := filepath.Join(repo, name)
+		tu.IsNil(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		tu.IsNil(t, ioutil.WriteFile(filename, []byte(data), 0644))
+	}
+
+	git("init", "-q")
+	write("etc/mysql/my.cnf", "[mysqld]\nmax_connections=100\n!includedir /etc/mysql/conf.d/\n")
+	write("etc/mysql/conf.d/tuning.cnf", "[mysqld]\ninnodb_buffer_pool_size=1G\n")
+	write("etc/mysql/conf.d/README", "not an option file")
+	git("add", ".")
+	git("commit", "-q", "-m", "v1")
+	git("tag", "v1")
+
+	write("etc/mysql/my.cnf", "[mysqld]\nmax_connections=500\n!includedir /etc/mysql/conf.d/\n")
+	write("etc/mysql/conf.d/tuning.cnf", "[mysqld]\ninnodb_buffer_pool_size=4G\n")
+	git("commit", "-q", "-a", "-m", "v2")
+
+	cnf, err := NewGitCNFReader(repo, "v1", "etc/mysql/my.cnf")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"max_connections":         "100",
+		"innodb_buffer_pool_size": "1G",
+	})
+	origin, ok := cnf.Origin("innodb_buffer_pool_size")
+	tu.Assert(t, ok, "innodb_buffer_pool_size should have an origin")
+	tu.Equals(t, origin, Origin{Path: "v1:etc/mysql/conf.d/tuning.cnf", Line: 2, Group: "mysqld"})
+
+	cnf, err = NewGitCNFReader(filepath.Join(repo, "etc"), "HEAD", "./mysql/my.cnf")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"max_connections":         "500",
+		"innodb_buffer_pool_size": "4G",
+	})
+
+	_, err = NewGitCNFReader(repo, "no-such-rev", "etc/mysql/my.cnf")
+	tu.NotNil(t, err)
+	_, err = NewGitCNFReader(repo, "v1", "etc/my.cnf")
+	tu.NotNil(t, err)
+}
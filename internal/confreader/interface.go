@@ -1,15 +1,102 @@
 package confreader
 
+import (
+	"fmt"
+	"strings"
+)
+
 type ConfigReader interface {
 	Get(string) (interface{}, bool)
 	Keys() []string
 	Entries() map[string]interface{}
 	Type() string
+	Origin(string) (Origin, bool)
+	Provenance(string) []Origin
+	// Description returns the description of a variable, if known.
+	Description(string) (string, bool)
+}
+
+// Origin describes where and how the value of an entry was set.
+type Origin struct {
+	// Source is the kind of source that set the value, like PERSISTED.
+	Source string `json:",omitempty"`
+	// Path is the file the value was read from.
+	Path string `json:",omitempty"`
+	// Line is the line of Path the value was read from.
+	Line int `json:",omitempty"`
+	// Group is the section of the file the value was read from.
+	Group   string `json:",omitempty"`
+	SetTime string `json:",omitempty"`
+	SetUser string `json:",omitempty"`
+	SetHost string `json:",omitempty"`
+	// Overridden is true if the value was replaced by a later one.
+	Overridden bool `json:",omitempty"`
+}
+
+func (o Origin) String() string {
+	parts := []string{}
+	if o.Source != "" {
+		parts = append(parts, o.Source)
+	}
+	if o.Path != "" && o.Line > 0 {
+		parts = append(parts, fmt.Sprintf("%s:%d", o.Path, o.Line))
+	} else if o.Path != "" {
+		parts = append(parts, o.Path)
+	}
+	if o.Group != "" {
+		parts = append(parts, "["+o.Group+"]")
+	}
+	if o.SetUser != "" || o.SetHost != "" {
+		parts = append(parts, "set by "+o.SetUser+"@"+o.SetHost)
+	}
+	if o.SetTime != "" {
+		parts = append(parts, "at "+o.SetTime)
+	}
+	if o.Overridden {
+		parts = append(parts, "(overridden)")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Unknown is the value of a variable that is set, but whose value cannot be
+// known from the source, like a Terraform expression using a variable. It is
+// the expression or the reason why it is unknown. Unknown values are not
+// compared.
+type Unknown string
+
+func (u Unknown) String() string {
+	return "<Unknown: " + string(u) + ">"
+}
+
+// MarshalText shows unknown values in JSON like in text.
+func (u Unknown) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
 }
 
 type Config struct {
 	ConfigType string
 	EntriesMap map[string]interface{}
+	OriginsMap map[string]Origin `json:",omitempty"`
+	// ProvenanceMap has every place a key was set, in read order, for
+	// sources like cnf files where a key can be set several times.
+	ProvenanceMap map[string][]Origin `json:",omitempty"`
+	// DescriptionsMap has the description of the variables, for sources
+	// like the mysqld --help output that describe them.
+	DescriptionsMap map[string]string `json:",omitempty"`
+	// Version is the version of the server the defaults were read from,
+	// like 8.0.36-28, when it is known.
+	Version string `json:",omitempty"`
+	// Subset is true if the config only has some of the variables of a
+	// server, like the built-in defaults catalog, so its keys cannot be used
+	// to expand abbreviated option names.
+	Subset bool `json:",omitempty"`
+	// Order has the keys in the order they were set, for sources where a
+	// later setting overrides a previous one. A key can appear several times.
+	Order []string `json:",omitempty"`
+	// Environment has the variables a source sets for the server image,
+	// like MYSQL_ALLOW_EMPTY_PASSWORD in a docker compose service. They are
+	// not mysqld options, so they are not entries and are not compared.
+	Environment map[string]string `json:",omitempty"`
 }
 
 func (c *Config) Keys() []string {
@@ -32,3 +119,35 @@ func (c *Config) Entries() map[string]interface{} {
 func (c *Config) Type() string {
 	return c.ConfigType
 }
+
+// Origin returns where the value of key was set, if known.
+func (c *Config) Origin(key string) (Origin, bool) {
+	if origin, ok := c.OriginsMap[key]; ok {
+		return origin, true
+	}
+	provenance := c.ProvenanceMap[key]
+	for i := len(provenance) - 1; i >= 0; i-- {
+		if !provenance[i].Overridden {
+			return provenance[i], true
+		}
+	}
+	return Origin{}, false
+}
+
+// Provenance returns every place key was set, in read order. Values replaced
+// by a later one are marked as Overridden.
+func (c *Config) Provenance(key string) []Origin {
+	if provenance, ok := c.ProvenanceMap[key]; ok {
+		return provenance
+	}
+	if origin, ok := c.OriginsMap[key]; ok {
+		return []Origin{origin}
+	}
+	return nil
+}
+
+// Description returns the description of key, if known.
+func (c *Config) Description(key string) (string, bool) {
+	description, ok := c.DescriptionsMap[key]
+	return description, ok
+}
@@ -0,0 +1,144 @@
+package confreader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// manifestConfigPaths has the path of the cnf text in the custom resources of
+// the Percona operators, by kind.
+var manifestConfigPaths = map[string][]string{
+	"PerconaXtraDBCluster": {"spec", "pxc", "configuration"},
+	"PerconaServerMySQL":   {"spec", "mysql", "configuration"},
+}
+
+type manifestResource struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec  map[interface{}]interface{} `yaml:"spec"`
+	Data  map[string]string           `yaml:"data"`
+	Items []manifestResource          `yaml:"items"`
+}
+
+// manifestConfig is a cnf text embedded in a manifest.
+type manifestConfig struct {
+	// id is <kind>/<name> for custom resources and ConfigMap/<name>/<key>
+	// for ConfigMaps.
+	id   string
+	text string
+}
+
+// NewManifestReader reads the cnf text embedded in a Kubernetes manifest: the
+// configuration of a Percona Operator custom resource (spec.pxc.configuration
+// of a PerconaXtraDBCluster or spec.mysql.configuration of a
+// PerconaServerMySQL) or a key of a ConfigMap. Manifests can have several YAML
+// documents and List resources.
+//
+// selector chooses the config as <kind>/<name> or ConfigMap/<name>/<key>. It
+// can be empty if the manifest has only one config. Without a key, only the
+// ConfigMap keys having a .cnf extension are taken as configs.
+func NewManifestReader(filename, selector string, groups ...string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read manifest")
+	}
+	defer f.Close()
+
+	configs, err := parseManifest(f, selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid manifest %s", filename)
+	}
+
+	var matches []manifestConfig
+	var ids []string
+	for _, c := range configs {
+		ids = append(ids, c.id)
+		if matchSelector(c.id, selector) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) != 1 {
+		sort.Strings(ids)
+		return nil, fmt.Errorf("choose one of the configs in %s: %s", filename, strings.Join(ids, ", "))
+	}
+
+	return NewCNFReaderFrom(strings.NewReader(matches[0].text), filename+"#"+matches[0].id, groups...)
+}
+
+// parseManifest returns the configs in all the documents in r. ConfigMap keys
+// are returned if they have a .cnf extension or if they are named in selector.
+func parseManifest(r io.Reader, selector string) ([]manifestConfig, error) {
+	var configs []manifestConfig
+	dec := yaml.NewDecoder(r)
+	for {
+		var res manifestResource
+		err := dec.Decode(&res)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, resourceConfigs(res, selector)...)
+	}
+	return configs, nil
+}
+
+func resourceConfigs(res manifestResource, selector string) []manifestConfig {
+	var configs []manifestConfig
+	for _, item := range res.Items {
+		configs = append(configs, resourceConfigs(item, selector)...)
+	}
+
+	id := res.Kind + "/" + res.Metadata.Name
+
+	if res.Kind == "ConfigMap" {
+		for key, text := range res.Data {
+			keyID := id + "/" + key
+			if path.Ext(key) == ".cnf" || strings.EqualFold(keyID, selector) {
+				configs = append(configs, manifestConfig{id: keyID, text: text})
+			}
+		}
+		return configs
+	}
+
+	if fields, ok := manifestConfigPaths[res.Kind]; ok {
+		var value interface{} = res.Spec
+		for _, field := range fields[1:] {
+			m, _ := value.(map[interface{}]interface{})
+			value = m[field]
+		}
+		if text, ok := value.(string); ok {
+			configs = append(configs, manifestConfig{id: id, text: text})
+		}
+	}
+	return configs
+}
+
+// matchSelector returns true if selector is empty or if it is id or the
+// <kind>/<name> part of a ConfigMap id. Kinds are case insensitive.
+func matchSelector(id, selector string) bool {
+	if selector == "" {
+		return true
+	}
+	parts, sel := strings.SplitN(id, "/", 3), strings.SplitN(selector, "/", 3)
+	if len(sel) < 2 || len(sel) > len(parts) || !strings.EqualFold(parts[0], sel[0]) {
+		return false
+	}
+	for i := 1; i < len(sel); i++ {
+		if parts[i] != sel[i] {
+			return false
+		}
+	}
+	return true
+}
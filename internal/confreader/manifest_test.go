@@ -0,0 +1,41 @@
+package confreader
+
+import (
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestManifestReader(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"PerconaXtraDBCluster/cluster1": {
+			"wsrep_debug":             "CLIENT",
+			"max_connections":         "500",
+			"innodb_buffer_pool_size": "2G",
+		},
+		"perconaserverMySQL/ps-cluster1": {
+			"max_connections": "250",
+		},
+		"ConfigMap/mysql-config/my.cnf": {
+			"max_connections":   "300",
+			"skip-name-resolve": "ON",
+		},
+	}
+	for selector, want := range tests {
+		cnf, err := NewManifestReader("testdata/manifests/cluster.yaml", selector)
+		tu.IsNil(t, err)
+		tu.Equals(t, cnf.Entries(), want)
+	}
+
+	cnf, err := NewManifestReader("testdata/manifests/cluster.yaml", "ConfigMap/mysql-config/tuning.cnf")
+	tu.IsNil(t, err)
+	origin, ok := cnf.Origin("innodb_log_file_size")
+	tu.Assert(t, ok, "innodb_log_file_size should have an origin")
+	tu.Equals(t, origin, Origin{Path: "testdata/manifests/cluster.yaml#ConfigMap/mysql-config/tuning.cnf", Line: 2, Group: "mysqld"})
+
+	// Ambiguous and missing selectors and keys that are not cnf files.
+	for _, selector := range []string{"", "ConfigMap/mysql-config", "ConfigMap/other", "ConfigMap/mysql-config/init.sql"} {
+		_, err = NewManifestReader("testdata/manifests/cluster.yaml", selector)
+		tu.NotNil(t, err)
+	}
+}
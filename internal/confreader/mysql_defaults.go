@@ -5,52 +5,134 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
+// helpOptionRe matches the first line of an option in the header of the
+// mysqld --verbose --help output, like:
+//
+//	-b, --basedir=name  Path to installation directory.
+//	--innodb[=name]     Enable InnoDB plugin
+//
+// The submatches are the option name and the start of its description.
+var helpOptionRe = regexp.MustCompile(`^  (?:-\S, )?--([A-Za-z0-9_-]+)(?:=\S*|\[=\S*\])?(?:\s+(.*))?$`)
+
+// helpVersionRe matches the first line of the mysqld --help output, like
+// "/usr/sbin/mysqld  Ver 8.0.36 for Linux on x86_64 (MySQL Community Server - GPL)".
+// The submatch is the server version.
+var helpVersionRe = regexp.MustCompile(`^\S+\s+Ver\s+(\S+)`)
+
+// helpLogRe matches the messages the server writes to the error log while
+// printing the help, like the deprecation warnings of MySQL 8.0, which are
+// mixed with the output if stderr is captured too.
+var helpLogRe = regexp.MustCompile(`\[(Warning|Note|ERROR|System)\]`)
+
+// defaultsNameRe matches the name of a variable in the defaults table.
+var defaultsNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
 // How to get defaults:
 // touch /tmp/my.cnf
 // mysqld --defaults-file=/tmp/my.cnf --verbose --help > /tmp/defaultvals
 
 func NewDefaultsParser(filename string) (ConfigReader, error) {
-	f, err := os.Open(cleanFilename(filename))
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot read defaults file")
 	}
 	defer f.Close()
 
-	return parseFile(f)
+	return parseFile(f, filename)
 }
 
-func parseFile(r io.Reader) (ConfigReader, error) {
-	cnf := &Config{ConfigType: "defaults", EntriesMap: make(map[string]interface{})}
+// NewDefaultsParserFrom reads mysqld --verbose --help output from r. filename
+// is used in error messages.
+func NewDefaultsParserFrom(r io.Reader, filename string) (ConfigReader, error) {
+	return parseFile(r, filename)
+}
+
+// parseFile reads the output of mysqld --verbose --help, as printed by MySQL,
+// Percona Server and MariaDB. The header has the server version and the
+// description of every option, and the table after the ----- line has the
+// default values. The table ends at the first empty line.
+//
+// Values start at the column of the second run of dashes in the ----- line.
+// Names too long for the name column are followed by the value after a
+// single space or, if the output was wrapped, in the next line, which is
+// indented. Log messages mixed with the output are skipped. Lines that are
+// not table rows, like a value starting before its column, are reported as
+// errors.
+func parseFile(r io.Reader, filename string) (ConfigReader, error) {
+	cnf := &Config{
+		ConfigType:      "defaults",
+		EntriesMap:      make(map[string]interface{}),
+		DescriptionsMap: make(map[string]string),
+	}
 	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
 
 	inHeader := true
+	option := ""
+	// key is the variable in the previous row, whose value can continue in
+	// the next line.
+	key := ""
+	valueColumn := 0
+	lineNo := 0
 	for s.Scan() {
-		t := s.Text()
+		lineNo++
+		t := strings.TrimRight(s.Text(), "\r")
+		if helpLogRe.MatchString(t) {
+			continue
+		}
 		if inHeader {
+			if m := helpVersionRe.FindStringSubmatch(t); m != nil && cnf.Version == "" {
+				cnf.Version = m[1]
+			}
 			if strings.HasPrefix(t, "-----") {
 				inHeader = false
+				valueColumn = strings.IndexByte(t, ' ') + 1
 			}
+			option = parseHelpLine(cnf.DescriptionsMap, option, t)
 			continue
 		}
 		if strings.TrimSpace(t) == "" {
 			break
 		}
 
-		parts := strings.SplitN(t, " ", 2)
-		key := strings.Replace(strings.TrimSpace(parts[0]), "-", "_", -1)
-		val := ""
-		if len(parts) == 2 {
-			val = strings.TrimSpace(parts[1])
+		if t[0] == ' ' || t[0] == '\t' {
+			if key == "" {
+				return nil, fmt.Errorf("%s:%d: value without a variable name %q", filename, lineNo, t)
+			}
+			prev, ok := cnf.EntriesMap[key].(string)
+			if !ok {
+				continue
+			}
+			value := strings.TrimSpace(t)
+			if prev != "" {
+				value = prev + " " + value
+			}
+			cnf.EntriesMap[key] = defaultValue(value)
+			continue
+		}
+
+		name, val := t, ""
+		if i := strings.IndexAny(t, " \t"); i >= 0 {
+			name, val = t[:i], strings.TrimSpace(t[i:])
 		}
-		if val == "(No default value)" {
-			val = ""
+		if !defaultsNameRe.MatchString(name) {
+			return nil, fmt.Errorf("%s:%d: invalid variable name in %q", filename, lineNo, t)
 		}
-		cnf.EntriesMap[key] = val
+		if start := len(t) - len(strings.TrimLeft(t[len(name):], " \t")); val != "" && start < valueColumn {
+			return nil, fmt.Errorf("%s:%d: the value of %s doesn't start at column %d in %q", filename, lineNo, name, valueColumn+1, t)
+		}
+		key = strings.Replace(name, "-", "_", -1)
+		cnf.EntriesMap[key] = defaultValue(val)
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrapf(err, "cannot read %s", filename)
 	}
 
 	if len(cnf.EntriesMap) == 0 {
@@ -59,3 +141,42 @@ func parseFile(r io.Reader) (ConfigReader, error) {
 
 	return cnf, nil
 }
+
+// defaultValue returns the value of a row of the defaults table. Variables
+// without a default are shown as (No default value) and read as empty, and
+// the ones marked as HostDependent in the catalog are Unknown.
+func defaultValue(value string) interface{} {
+	switch value {
+	case "(No default value)":
+		return ""
+	case HostDependent:
+		return Unknown("depends on the host")
+	}
+	return value
+}
+
+// parseHelpLine adds a line of the option descriptions in the header of the
+// mysqld --help output to descriptions. option is the option being described
+// by the previous lines, if any, and the option described by line is returned.
+// Descriptions start next to the option name or in the following line and
+// continue in the indented lines below it.
+func parseHelpLine(descriptions map[string]string, option, line string) string {
+	if m := helpOptionRe.FindStringSubmatch(line); m != nil {
+		option = strings.Replace(m[1], "-", "_", -1)
+		if m[2] != "" {
+			descriptions[option] = strings.TrimSpace(m[2])
+		}
+		return option
+	}
+
+	text := strings.TrimSpace(line)
+	if option == "" || text == "" || !strings.HasPrefix(line, "    ") {
+		return ""
+	}
+	if descriptions[option] == "" {
+		descriptions[option] = text
+	} else {
+		descriptions[option] += " " + text
+	}
+	return option
+}
@@ -13,3 +13,67 @@ func TestDefaultsReader(t *testing.T) {
 	tu.LoadJson(t, "want_defaults.json", &want)
 	tu.Equals(t, cnf, want)
 }
+
+func TestParseHelpLine(t *testing.T) {
+	descriptions := make(map[string]string)
+	option := ""
+	for _, line := range []string{
+		"--print-defaults        Print the program argument list and exit.",
+		"",
+		"  -b, --basedir=name  Path to installation directory. All paths are usually",
+		"                      resolved relative to this.",
+		"  --binlog-format=name ",
+		"                      What form of binary logging the master will use.",
+		"  --innodb[=name]     Enable or disable InnoDB plugin.",
+		"  --skip-grant-tables Start without grant tables.",
+		"",
+		"Variables (--variable-name=value)",
+	} {
+		option = parseHelpLine(descriptions, option, line)
+	}
+
+	tu.Equals(t, descriptions, map[string]string{
+		"basedir":           "Path to installation directory. All paths are usually resolved relative to this.",
+		"binlog_format":     "What form of binary logging the master will use.",
+		"innodb":            "Enable or disable InnoDB plugin.",
+		"skip_grant_tables": "Start without grant tables.",
+	})
+}
+
+func TestDefaultsReaderLayouts(t *testing.T) {
+	cnf, err := NewDefaultsParser("testdata/defaults/mysql-8.0-warnings.txt")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.(*Config).Version, "8.0.36")
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"binlog_expire_logs_seconds": "2592000",
+		"ft_boolean_syntax":          `+ -><()~*:""&|`,
+		"init_connect":               "",
+		"max_connections":            "151",
+		"performance_schema_consumer_events_statements_history_long":   "FALSE",
+		"performance_schema_consumer_events_transactions_history_long": "FALSE",
+		"performance_schema_max_memory_classes":                        "450",
+		"sql_mode":                                                     "ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION",
+		"ssl_ca":                                                       "",
+		"tmpdir":                                                       "/tmp",
+	})
+	description, _ := cnf.Description("binlog_expire_logs_seconds")
+	tu.Equals(t, description, "If non-zero, binary logs will be purged after binlog_expire_logs_seconds seconds.")
+
+	cnf, err = NewDefaultsParser("testdata/defaults/mariadb-10.11.txt")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.(*Config).Version, "10.11.7-MariaDB-1:10.11.7+maria~ubu2204")
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"alter_algorithm":         "DEFAULT",
+		"innodb_buffer_pool_size": "134217728",
+		"optimizer_switch":        "index_merge=on,index_merge_union=on,index_merge_sort_union=on,index_merge_intersection=on",
+		"performance_schema_consumer_events_statements_history_long": "FALSE",
+		"version_comment": "mariadb.org binary distribution",
+		"wsrep_provider":  "none",
+	})
+	description, _ = cnf.Description("alter_algorithm")
+	tu.Equals(t, description, "Specify the alter table algorithm. One of: DEFAULT, COPY, INPLACE, NOCOPY, INSTANT")
+
+	_, err = NewDefaultsParser("testdata/defaults/malformed.txt")
+	tu.NotNil(t, err)
+	tu.Equals(t, err.Error(), `testdata/defaults/malformed.txt:7: the value of max doesn't start at column 60 in "max connections: 151"`)
+}
@@ -2,13 +2,22 @@ package confreader
 
 import (
 	"database/sql"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
 )
 
+const variablesInfoQuery = "SELECT VARIABLE_NAME, VARIABLE_SOURCE, VARIABLE_PATH, SET_TIME, SET_USER, SET_HOST " +
+	"FROM performance_schema.variables_info"
+
+var variablesInfoVersion = version.Must(version.NewVersion("8.0.0"))
+
 func NewMySQLReader(db *sql.DB) (ConfigReader, error) {
 	rows, err := db.Query("SHOW GLOBAL VARIABLES")
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	ini := &Config{ConfigType: "mysql", EntriesMap: make(map[string]interface{})}
 
@@ -20,5 +29,54 @@ func NewMySQLReader(db *sql.DB) (ConfigReader, error) {
 		}
 		ini.EntriesMap[key] = val
 	}
+
+	if hasVariablesInfo(ini) {
+		ini.OriginsMap = readVariablesInfo(db)
+	}
+
 	return ini, nil
 }
+
+// hasVariablesInfo returns true if the server has the
+// performance_schema.variables_info table, added in MySQL 8.0.
+func hasVariablesInfo(cfg *Config) bool {
+	vs, _ := cfg.EntriesMap["version"].(string)
+	if vs == "" || strings.Contains(strings.ToLower(vs), "mariadb") {
+		return false
+	}
+	v, err := version.NewVersion(vs)
+	if err != nil {
+		return false
+	}
+	return !v.LessThan(variablesInfoVersion)
+}
+
+// readVariablesInfo returns the origin of every variable. Since origins are
+// informative, errors like a disabled performance_schema are ignored and
+// the origins read so far are returned.
+func readVariablesInfo(db *sql.DB) map[string]Origin {
+	origins := make(map[string]Origin)
+
+	rows, err := db.Query(variablesInfoQuery)
+	if err != nil {
+		return origins
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, source string
+		var path, setTime, setUser, setHost sql.NullString
+		if err := rows.Scan(&name, &source, &path, &setTime, &setUser, &setHost); err != nil {
+			continue
+		}
+		origins[name] = Origin{
+			Source:  source,
+			Path:    path.String,
+			SetTime: setTime.String,
+			SetUser: setUser.String,
+			SetHost: setHost.String,
+		}
+	}
+
+	return origins
+}
@@ -0,0 +1,359 @@
+package confreader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// option is an option read from an option file.
+type option struct {
+	Name  string
+	Value string
+	Group string
+	File  string
+	Line  int
+	// Source is the kind of setting the option comes from, when the file has
+	// more than one, like COMMAND_LINE.
+	Source string
+}
+
+// fileSystem is where option files and the files they include are read from.
+type fileSystem interface {
+	// abs returns the name identifying a file, used to detect include cycles.
+	abs(name string) (string, error)
+	open(name string) (io.ReadCloser, error)
+	// readDir returns the names of the files in a directory.
+	readDir(name string) ([]string, error)
+}
+
+// osFileSystem reads the local files.
+type osFileSystem struct{}
+
+func (osFileSystem) abs(name string) (string, error) {
+	return filepath.Abs(name)
+}
+
+func (osFileSystem) open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osFileSystem) readDir(name string) ([]string, error) {
+	files, err := ioutil.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, fi := range files {
+		if !fi.IsDir() {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+// optionFileParser reads MySQL option files following the same rules mysqld
+// uses (see search_default_file_with_ext in mysys/my_default.cc).
+type optionFileParser struct {
+	// groups to read options from. Options in other groups are ignored.
+	groups []string
+	// root is prepended to absolute include paths when reading the files of
+	// another host. Empty means the local filesystem root.
+	root string
+	// stack holds the files currently being read and is used to detect cycles.
+	stack []string
+	// options read so far, in read order.
+	options []option
+	// fs is where the files are read from.
+	fs fileSystem
+}
+
+func newOptionFileParser(groups []string) *optionFileParser {
+	return &optionFileParser{groups: groups, fs: osFileSystem{}}
+}
+
+func (p *optionFileParser) parseFile(filename string) error {
+	abs, err := p.fs.abs(filename)
+	if err != nil {
+		return err
+	}
+	for _, f := range p.stack {
+		if f == abs {
+			return fmt.Errorf("include cycle detected: %s -> %s", strings.Join(p.stack, " -> "), abs)
+		}
+	}
+
+	f, err := p.fs.open(abs)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	p.stack = append(p.stack, abs)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+
+	return p.parse(f, abs)
+}
+
+// parseDir reads every option file in dir. Like mysqld, only files having a
+// .cnf extension are read, in alphabetical order.
+func (p *optionFileParser) parseDir(dir string) error {
+	files, err := p.fs.readDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range files {
+		if filepath.Ext(name) == ".cnf" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := p.parseFile(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parse reads the options in r. filename is used to resolve relative include
+// paths and in error messages.
+func (p *optionFileParser) parse(r io.Reader, filename string) error {
+	s := bufio.NewScanner(r)
+	group := ""
+	inGroup := false
+	lineNo := 0
+
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimSpace(s.Text())
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '!' {
+			directive, arg := parseIncludeDirective(line)
+			if directive == "" {
+				return fmt.Errorf("%s:%d: unknown directive %q", filename, lineNo, line)
+			}
+			var err error
+			switch directive {
+			case "!include":
+				err = p.parseFile(p.resolveIncludePath(filename, arg))
+			case "!includedir":
+				err = p.parseDir(p.resolveIncludePath(filename, arg))
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return fmt.Errorf("%s:%d: wrong group definition %q", filename, lineNo, line)
+			}
+			group = strings.ToLower(strings.TrimSpace(line[1:end]))
+			inGroup = hasGroup(p.groups, group)
+			continue
+		}
+
+		if group == "" {
+			return fmt.Errorf("%s:%d: found option without preceding group", filename, lineNo)
+		}
+		if !inGroup {
+			continue
+		}
+
+		name, value := parseOptionLine(line)
+		if name == "" {
+			return fmt.Errorf("%s:%d: invalid option %q", filename, lineNo, line)
+		}
+		p.options = append(p.options, option{Name: name, Value: value, Group: group, File: filename, Line: lineNo})
+	}
+
+	return s.Err()
+}
+
+// parseOptionLine returns the name and value of an option line having no
+// leading spaces. Comments after the value are removed, the quotes around
+// the value are stripped and escape sequences are replaced.
+// Options without a value, like skip-name-resolve, are returned as ON.
+func parseOptionLine(line string) (string, string) {
+	line = strings.TrimSpace(removeEndComment(line))
+
+	eq := strings.IndexByte(line, '=')
+	if eq < 0 {
+		return line, "ON"
+	}
+
+	name := strings.TrimSpace(line[:eq])
+	value := strings.TrimSpace(line[eq+1:])
+
+	if len(value) > 1 && (value[0] == '\'' || value[0] == '"') && value[0] == value[len(value)-1] {
+		value = value[1 : len(value)-1]
+	}
+
+	return name, unescapeValue(value)
+}
+
+// removeEndComment removes a # comment at the end of the line. A # inside a
+// quoted string doesn't start a comment.
+func removeEndComment(line string) string {
+	var quote byte
+	escape := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case (c == '\'' || c == '"') && !escape:
+			if quote == 0 {
+				quote = c
+			} else if quote == c {
+				quote = 0
+			}
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+		escape = quote != 0 && c == '\\' && !escape
+	}
+	return line
+}
+
+// unescapeValue replaces the escape sequences mysqld understands in option
+// values. A backslash followed by any other character is kept as is.
+func unescapeValue(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i == len(value)-1 {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 's':
+			b.WriteByte(' ')
+		case '"', '\'', '\\':
+			b.WriteByte(value[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// parseIncludeDirective returns the directive (!include or !includedir) and
+// its argument if line is an include directive.
+func parseIncludeDirective(line string) (string, string) {
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return "", ""
+	}
+	switch parts[0] {
+	case "!include", "!includedir":
+		return parts[0], strings.TrimSpace(strings.TrimPrefix(line, parts[0]))
+	}
+	return "", ""
+}
+
+// resolveIncludePath returns the path of an included file. Relative paths are
+// resolved against the directory of the file having the directive and
+// absolute paths against the parser root.
+func (p *optionFileParser) resolveIncludePath(parent, path string) string {
+	path = cleanFilename(path)
+	if filepath.IsAbs(path) {
+		return filepath.Join(p.root, path)
+	}
+	return filepath.Join(filepath.Dir(parent), path)
+}
+
+// hasGroup returns true if group is one of groups. Like in mysqld, group
+// names are case insensitive.
+func hasGroup(groups []string, group string) bool {
+	group = strings.TrimSpace(group)
+	for _, g := range groups {
+		if strings.EqualFold(g, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// newOptionsConfig returns a config having the options in the order they were
+// read, so later options override the previous ones. Different spellings of
+// the same option, like max-connections and max_connections, are the same
+// option and the key keeps the spelling of the option that was read last.
+// Cumulative options keep all their values. The file, line and group of every
+// option are kept in the config provenance and the read order in Order.
+func newOptionsConfig(configType string, options []option) *Config {
+	cnf := &Config{
+		ConfigType:    configType,
+		EntriesMap:    make(map[string]interface{}),
+		ProvenanceMap: make(map[string][]Origin),
+	}
+	// keys holds the key used in EntriesMap for each canonical option name.
+	keys := make(map[string]string)
+
+	for _, opt := range options {
+		name, _ := CanonicalName(opt.Name, nil)
+		origin := Origin{Source: opt.Source, Path: opt.File, Line: opt.Line, Group: opt.Group}
+
+		key, seen := keys[name]
+		if !seen {
+			key = opt.Name
+		}
+
+		if IsCumulative(opt.Name) {
+			values, _ := cnf.EntriesMap[key].([]string)
+			cnf.EntriesMap[key] = append(values, opt.Value)
+			cnf.Order = append(cnf.Order, key)
+			cnf.ProvenanceMap[key] = append(cnf.ProvenanceMap[key], origin)
+			keys[name] = key
+			continue
+		}
+
+		provenance := cnf.ProvenanceMap[key]
+		for i := range provenance {
+			provenance[i].Overridden = true
+		}
+		if key != opt.Name {
+			delete(cnf.EntriesMap, key)
+			delete(cnf.ProvenanceMap, key)
+		}
+
+		cnf.EntriesMap[opt.Name] = opt.Value
+		cnf.Order = append(cnf.Order, opt.Name)
+		cnf.ProvenanceMap[opt.Name] = append(provenance, origin)
+		keys[name] = opt.Name
+	}
+
+	return cnf
+}
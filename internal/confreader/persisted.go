@@ -0,0 +1,112 @@
+package confreader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Sections of mysqld-auto.cnf. Variables set with SET PERSIST are stored in
+// persistedServerSection while the ones set with SET PERSIST_ONLY, that are
+// applied only on restart, are stored in nested sections like
+// mysql_server_static_options.
+const persistedServerSection = "mysql_server"
+
+type persistedVariable struct {
+	Value    json.RawMessage
+	Metadata struct {
+		Timestamp int64
+		User      string
+		Host      string
+	}
+}
+
+// NewPersistedReader reads the variables persisted with SET PERSIST and SET
+// PERSIST_ONLY from a MySQL 8.0+ mysqld-auto.cnf file, usually found in the
+// datadir. The metadata of every variable is available as its Origin.
+func NewPersistedReader(filename string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read persisted variables file")
+	}
+	defer f.Close()
+
+	return NewPersistedReaderFrom(f, filename)
+}
+
+// NewPersistedReaderFrom reads mysqld-auto.cnf persisted variables from r.
+// filename is used in origins.
+func NewPersistedReaderFrom(r io.Reader, filename string) (ConfigReader, error) {
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "invalid persisted variables file")
+	}
+
+	var server map[string]json.RawMessage
+	if err := json.Unmarshal(doc[persistedServerSection], &server); err != nil || server == nil {
+		return nil, fmt.Errorf("invalid persisted variables file. There is no %s section", persistedServerSection)
+	}
+
+	cnf := &Config{
+		ConfigType: "persisted",
+		EntriesMap: make(map[string]interface{}),
+		OriginsMap: make(map[string]Origin),
+	}
+	addPersisted(cnf, persistedServerSection, server, filename)
+
+	// Nested sections like mysql_server_static_options hold variables too.
+	for name, raw := range server {
+		var section map[string]json.RawMessage
+		if json.Unmarshal(raw, &section) != nil || isPersistedVariable(section) {
+			continue
+		}
+		addPersisted(cnf, name, section, filename)
+	}
+
+	return cnf, nil
+}
+
+// addPersisted adds the variables in a mysqld-auto.cnf section to cnf.
+// Entries not having a value, like nested sections, are skipped.
+func addPersisted(cnf *Config, section string, vars map[string]json.RawMessage, filename string) {
+	for name, raw := range vars {
+		var v persistedVariable
+		if json.Unmarshal(raw, &v) != nil || v.Value == nil {
+			continue
+		}
+
+		cnf.EntriesMap[name] = persistedValue(v.Value)
+		origin := Origin{
+			Source:  "PERSISTED",
+			Path:    filename,
+			Group:   section,
+			SetUser: v.Metadata.User,
+			SetHost: v.Metadata.Host,
+		}
+		if v.Metadata.Timestamp > 0 {
+			// Timestamps are stored as microseconds since the epoch.
+			origin.SetTime = time.Unix(0, v.Metadata.Timestamp*1000).UTC().Format("2006-01-02 15:04:05.000000")
+		}
+		cnf.OriginsMap[name] = origin
+	}
+}
+
+func isPersistedVariable(section map[string]json.RawMessage) bool {
+	_, ok := section["Value"]
+	return ok
+}
+
+// persistedValue returns a persisted value as a string. MySQL stores all
+// values as JSON strings but other JSON types are accepted too.
+func persistedValue(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,52 @@
+package confreader
+
+import (
+	"strings"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestPersistedReader(t *testing.T) {
+	cnf, err := NewPersistedReader("testdata/mysqld-auto.cnf")
+	tu.IsNil(t, err)
+
+	want := &Config{
+		ConfigType: "persisted",
+		EntriesMap: map[string]interface{}{
+			"max_connections":      "500",
+			"sql_mode":             "STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION",
+			"innodb_log_file_size": "1073741824",
+		},
+		OriginsMap: map[string]Origin{
+			"max_connections": Origin{
+				Source:  "PERSISTED",
+				Path:    "testdata/mysqld-auto.cnf",
+				Group:   "mysql_server",
+				SetTime: "2018-03-01 16:22:21.372531",
+				SetUser: "root",
+				SetHost: "localhost",
+			},
+			"sql_mode": Origin{
+				Source:  "PERSISTED",
+				Path:    "testdata/mysqld-auto.cnf",
+				Group:   "mysql_server",
+				SetTime: "2018-03-01 16:22:32.000000",
+				SetUser: "admin",
+				SetHost: "10.0.0.5",
+			},
+			"innodb_log_file_size": Origin{
+				Source:  "PERSISTED",
+				Path:    "testdata/mysqld-auto.cnf",
+				Group:   "mysql_server_static_options",
+				SetTime: "2018-03-01 16:22:40.000000",
+				SetUser: "root",
+				SetHost: "localhost",
+			},
+		},
+	}
+	tu.Equals(t, cnf, want)
+
+	_, err = NewPersistedReaderFrom(strings.NewReader(`{"Version": 1}`), "mysqld-auto.cnf")
+	tu.NotNil(t, err)
+}
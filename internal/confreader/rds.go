@@ -0,0 +1,267 @@
+package confreader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// RDSInstance has the instance values RDS parameter formulas can use.
+// Formulas using a zero value are not evaluated.
+type RDSInstance struct {
+	// Memory is the DBInstanceClassMemory in bytes.
+	Memory int64
+	// VCPU is the DBInstanceVCPU.
+	VCPU int64
+}
+
+type rdsParameters struct {
+	Parameters []struct {
+		ParameterName  string
+		ParameterValue *string
+		Source         string
+	}
+}
+
+// NewRDSReader reads the parameters exported from an AWS RDS or Aurora
+// parameter group with aws rds describe-db-parameters or
+// describe-db-cluster-parameters. Only the parameters set in the group, having
+// the user Source, are read: the engine-default and system ones are the
+// defaults of RDS, and the parameters without a value are not set at all.
+//
+// Formulas like {DBInstanceClassMemory*3/4} or
+// GREATEST({DBInstanceClassMemory/9531392},5000) are evaluated using the
+// instance values. Formulas that cannot be evaluated are kept as is.
+func NewRDSReader(filename string, instance RDSInstance) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read RDS parameters file")
+	}
+	defer f.Close()
+
+	return NewRDSReaderFrom(f, filename, instance)
+}
+
+// NewRDSReaderFrom reads RDS parameters from r. filename is used in origins. See
+// NewRDSReader.
+func NewRDSReaderFrom(r io.Reader, filename string, instance RDSInstance) (ConfigReader, error) {
+	var doc rdsParameters
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "invalid RDS parameters file")
+	}
+	if doc.Parameters == nil {
+		return nil, fmt.Errorf("invalid RDS parameters file. There is no Parameters list")
+	}
+
+	cnf := &Config{
+		ConfigType: "rds",
+		EntriesMap: make(map[string]interface{}),
+		OriginsMap: make(map[string]Origin),
+	}
+	for _, p := range doc.Parameters {
+		if p.ParameterValue == nil || (p.Source != "" && p.Source != "user") {
+			continue
+		}
+		value := *p.ParameterValue
+		if v, err := evalRDSFormula(value, instance); err == nil {
+			value = v
+		}
+		cnf.EntriesMap[p.ParameterName] = value
+		cnf.Order = append(cnf.Order, p.ParameterName)
+		cnf.OriginsMap[p.ParameterName] = Origin{
+			Source: strings.ToUpper(strings.Replace(p.Source, "-", "_", -1)),
+			Path:   filename,
+		}
+	}
+
+	return cnf, nil
+}
+
+// evalRDSFormula returns the value of an RDS formula. Values without a
+// formula are returned as is. Like in RDS, the formulas use integer
+// arithmetic and support the GREATEST, LEAST and SUM functions.
+func evalRDSFormula(value string, instance RDSInstance) (string, error) {
+	if !strings.Contains(value, "{") {
+		return value, nil
+	}
+
+	expr := strings.NewReplacer("{", "", "}", "").Replace(value)
+	e := &rdsExpr{tokens: tokenizeRDSFormula(expr), instance: instance}
+	n, err := e.parseSum()
+	if err != nil {
+		return "", err
+	}
+	if e.pos < len(e.tokens) {
+		return "", fmt.Errorf("unexpected %q in %q", e.tokens[e.pos], value)
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+func tokenizeRDSFormula(expr string) []string {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(expr) && (unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j])) || expr[j] == '.' || expr[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// rdsExpr is a recursive descent parser evaluating RDS formulas.
+type rdsExpr struct {
+	tokens   []string
+	pos      int
+	instance RDSInstance
+}
+
+func (e *rdsExpr) next() string {
+	if e.pos >= len(e.tokens) {
+		return ""
+	}
+	return e.tokens[e.pos]
+}
+
+func (e *rdsExpr) expect(token string) error {
+	if e.next() != token {
+		return fmt.Errorf("expected %q", token)
+	}
+	e.pos++
+	return nil
+}
+
+// parseSum parses term (+|- term)*.
+func (e *rdsExpr) parseSum() (int64, error) {
+	n, err := e.parseTerm()
+	for err == nil && (e.next() == "+" || e.next() == "-") {
+		op := e.next()
+		e.pos++
+		var m int64
+		if m, err = e.parseTerm(); op == "+" {
+			n += m
+		} else {
+			n -= m
+		}
+	}
+	return n, err
+}
+
+// parseTerm parses factor (*|/ factor)*.
+func (e *rdsExpr) parseTerm() (int64, error) {
+	n, err := e.parseFactor()
+	for err == nil && (e.next() == "*" || e.next() == "/") {
+		op := e.next()
+		e.pos++
+		var m int64
+		if m, err = e.parseFactor(); err != nil {
+			break
+		}
+		if op == "*" {
+			n *= m
+		} else if m == 0 {
+			err = fmt.Errorf("division by zero")
+		} else {
+			n /= m
+		}
+	}
+	return n, err
+}
+
+// parseFactor parses numbers, variables, function calls and (sum).
+func (e *rdsExpr) parseFactor() (int64, error) {
+	token := e.next()
+	e.pos++
+
+	switch {
+	case token == "(":
+		n, err := e.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		return n, e.expect(")")
+	case token == "-":
+		n, err := e.parseFactor()
+		return -n, err
+	case token != "" && unicode.IsDigit(rune(token[0])):
+		return strconv.ParseInt(token, 10, 64)
+	}
+
+	if e.next() == "(" {
+		return e.parseFunction(token)
+	}
+
+	var value int64
+	switch token {
+	case "DBInstanceClassMemory":
+		value = e.instance.Memory
+	case "DBInstanceVCPU":
+		value = e.instance.VCPU
+	default:
+		return 0, fmt.Errorf("unknown variable %q", token)
+	}
+	if value == 0 {
+		return 0, fmt.Errorf("the value of %s is unknown", token)
+	}
+	return value, nil
+}
+
+func (e *rdsExpr) parseFunction(name string) (int64, error) {
+	name = strings.ToUpper(name)
+	switch name {
+	case "GREATEST", "LEAST", "SUM":
+	default:
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+
+	e.pos++ // (
+	var args []int64
+	for {
+		n, err := e.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, n)
+		if e.next() != "," {
+			break
+		}
+		e.pos++
+	}
+	if err := e.expect(")"); err != nil {
+		return 0, err
+	}
+
+	result := args[0]
+	for _, n := range args[1:] {
+		switch name {
+		case "GREATEST":
+			if n > result {
+				result = n
+			}
+		case "LEAST":
+			if n < result {
+				result = n
+			}
+		case "SUM":
+			result += n
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package confreader
+
+import (
+	"strings"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestRDSReader(t *testing.T) {
+	cnf, err := NewRDSReader("testdata/cloud/rds-parameters.json", RDSInstance{Memory: 16 << 30})
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Type(), "rds")
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"max_connections": "1802",
+		// DBInstanceVCPU is unknown.
+		"innodb_read_io_threads": "{GREATEST(DBInstanceVCPU/2,4)}",
+		"slow_query_log":         "1",
+	})
+	// The engine-default and system parameters are the RDS defaults.
+	_, ok := cnf.Get("innodb_buffer_pool_size")
+	tu.Assert(t, !ok, "innodb_buffer_pool_size has the system source")
+
+	origin, ok := cnf.Origin("slow_query_log")
+	tu.Assert(t, ok, "slow_query_log should have an origin")
+	tu.Equals(t, origin, Origin{Source: "USER", Path: "testdata/cloud/rds-parameters.json"})
+
+	_, err = NewRDSReaderFrom(strings.NewReader(`{"DBParameterGroups": []}`), "groups.json", RDSInstance{})
+	tu.NotNil(t, err)
+}
+
+func TestEvalRDSFormula(t *testing.T) {
+	instance := RDSInstance{Memory: 8 << 30, VCPU: 4}
+	tests := map[string]string{
+		"151":                              "151",
+		"{DBInstanceClassMemory*3/4}":      "6442450944",
+		"{DBInstanceClassMemory/12582880}": "682",
+		"GREATEST({DBInstanceClassMemory/9531392},5000)": "5000",
+		"{SUM(DBInstanceVCPU*2, 8) - (1 + 1)}":           "14",
+		"{10/3}":                                         "3",
+	}
+	for formula, want := range tests {
+		got, err := evalRDSFormula(formula, instance)
+		tu.IsNil(t, err)
+		tu.Equals(t, got, want)
+	}
+
+	for _, formula := range []string{"{log(DBInstanceClassMemory)}", "{EndPointPort}", "{1/0}", "{(1+2}", "{1 2}"} {
+		_, err := evalRDSFormula(formula, instance)
+		tu.NotNil(t, err)
+	}
+}
@@ -0,0 +1,39 @@
+package confreader
+
+// renamedVariables has the new name of the variables renamed in later server
+// versions, like the replication variables renamed in MySQL 8.0.26. The old
+// names are usually kept as deprecated aliases for a while.
+var renamedVariables = map[string]string{
+	"expire_logs_days":            "binlog_expire_logs_seconds",
+	"init_slave":                  "init_replica",
+	"log_slave_updates":           "log_replica_updates",
+	"log_slow_slave_statements":   "log_slow_replica_statements",
+	"master_verify_checksum":      "source_verify_checksum",
+	"rpl_stop_slave_timeout":      "rpl_stop_replica_timeout",
+	"skip_slave_start":            "skip_replica_start",
+	"slave_checkpoint_group":      "replica_checkpoint_group",
+	"slave_checkpoint_period":     "replica_checkpoint_period",
+	"slave_compressed_protocol":   "replica_compressed_protocol",
+	"slave_exec_mode":             "replica_exec_mode",
+	"slave_load_tmpdir":           "replica_load_tmpdir",
+	"slave_max_allowed_packet":    "replica_max_allowed_packet",
+	"slave_net_timeout":           "replica_net_timeout",
+	"slave_parallel_type":         "replica_parallel_type",
+	"slave_parallel_workers":      "replica_parallel_workers",
+	"slave_pending_jobs_size_max": "replica_pending_jobs_size_max",
+	"slave_preserve_commit_order": "replica_preserve_commit_order",
+	"slave_skip_errors":           "replica_skip_errors",
+	"slave_sql_verify_checksum":   "replica_sql_verify_checksum",
+	"slave_transaction_retries":   "replica_transaction_retries",
+	"slave_type_conversions":      "replica_type_conversions",
+	"sync_master_info":            "sync_source_info",
+	"tx_isolation":                "transaction_isolation",
+	"tx_read_only":                "transaction_read_only",
+}
+
+// RenamedVariable returns the name a variable has in later server versions,
+// if it was renamed. Names must be canonical, see CanonicalName.
+func RenamedVariable(name string) (string, bool) {
+	newName, ok := renamedVariables[name]
+	return newName, ok
+}
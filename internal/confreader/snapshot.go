@@ -0,0 +1,36 @@
+package confreader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// NewSnapshotReader reads a config saved as JSON, having the same fields as
+// Config:
+//
+//	{"ConfigType": "mysql", "EntriesMap": {"max_connections": "151"}}
+func NewSnapshotReader(filename string) (ConfigReader, error) {
+	f, err := os.Open(cleanFilename(filename))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read snapshot file")
+	}
+	defer f.Close()
+
+	return NewSnapshotReaderFrom(f)
+}
+
+// NewSnapshotReaderFrom reads a config saved as JSON from r.
+func NewSnapshotReaderFrom(r io.Reader) (ConfigReader, error) {
+	cnf := &Config{}
+	if err := json.NewDecoder(r).Decode(cnf); err != nil {
+		return nil, errors.Wrap(err, "invalid snapshot file")
+	}
+	if cnf.ConfigType == "" || cnf.EntriesMap == nil {
+		return nil, fmt.Errorf("Invalid snapshot file. ConfigType and EntriesMap are required")
+	}
+	return cnf, nil
+}
@@ -0,0 +1,19 @@
+package confreader
+
+import (
+	"strings"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestSnapshotReader(t *testing.T) {
+	cnf, err := NewSnapshotReader("testdata/want_defaults.json")
+	tu.IsNil(t, err)
+	want, err := NewDefaultsParser("testdata/defaults.txt")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf, want)
+
+	_, err = NewSnapshotReaderFrom(strings.NewReader(`{"EntriesMap": {}}`))
+	tu.NotNil(t, err)
+}
@@ -0,0 +1,188 @@
+package confreader
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// envVarRe matches the $VAR and ${VAR} references systemd expands in ExecStart.
+var envVarRe = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// systemdUnit holds the settings of a unit that define the mysqld arguments.
+type systemdUnit struct {
+	// execStart is the last ExecStart line and the file and line it was read from.
+	execStart string
+	file      string
+	line      int
+	env       map[string]string
+}
+
+// NewSystemdReader reads the mysqld options set in the ExecStart command of a
+// systemd unit file. Like systemd, the drop-in files in the <unit>.d directory
+// are read after the unit in alphabetical order, an empty ExecStart= resets the
+// command and the variables set with Environment= and EnvironmentFile=, like
+// MYSQLD_OPTS, are expanded.
+func NewSystemdReader(filename string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	u := &systemdUnit{env: make(map[string]string)}
+
+	if err := u.parseFile(filename); err != nil {
+		return nil, err
+	}
+
+	dropIns, _ := filepath.Glob(filepath.Join(filename+".d", "*.conf"))
+	sort.Strings(dropIns)
+	for _, dropIn := range dropIns {
+		if err := u.parseFile(dropIn); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.execStart == "" {
+		return nil, fmt.Errorf("%s: there is no ExecStart command", filename)
+	}
+
+	args, err := u.expand(u.execStart)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s:%d", u.file, u.line)
+	}
+	options, err := parseCommandLine(args, u.file, u.line)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s:%d", u.file, u.line)
+	}
+
+	return newOptionsConfig("args", options), nil
+}
+
+// parseFile reads the ExecStart, Environment and EnvironmentFile settings in
+// the [Service] section of a unit file.
+func (u *systemdUnit) parseFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "cannot read systemd unit")
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	section := ""
+	lineNo := 0
+
+	for s.Scan() {
+		lineNo++
+		start := lineNo
+		line := strings.TrimSpace(s.Text())
+		// A backslash at the end of a line continues it in the next one.
+		for strings.HasSuffix(line, "\\") && s.Scan() {
+			lineNo++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(s.Text())
+		}
+
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+		if section != "Service" {
+			continue
+		}
+
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(line[:eq]), strings.TrimSpace(line[eq+1:])
+
+		var err error
+		switch key {
+		case "ExecStart":
+			u.execStart, u.file, u.line = strings.TrimLeft(value, "@-:+!"), filename, start
+		case "Environment":
+			err = u.setEnvironment(value)
+		case "EnvironmentFile":
+			err = u.readEnvironmentFile(value)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "%s:%d", filename, start)
+		}
+	}
+
+	return s.Err()
+}
+
+// setEnvironment sets the variables in an Environment= setting. An empty
+// setting resets all the variables.
+func (u *systemdUnit) setEnvironment(value string) error {
+	if value == "" {
+		u.env = make(map[string]string)
+		return nil
+	}
+
+	assignments, err := splitWords(value)
+	if err != nil {
+		return err
+	}
+	for _, assignment := range assignments {
+		if eq := strings.IndexByte(assignment, '='); eq > 0 {
+			u.env[assignment[:eq]] = assignment[eq+1:]
+		}
+	}
+	return nil
+}
+
+// readEnvironmentFile sets the variables in an EnvironmentFile= file, like
+// /etc/sysconfig/mysql. Missing files are ignored if the name starts with -.
+func (u *systemdUnit) readEnvironmentFile(filename string) error {
+	optional := strings.HasPrefix(filename, "-")
+	filename = strings.TrimPrefix(filename, "-")
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if optional && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if err := u.setEnvironment(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expand splits the ExecStart command into words and replaces the environment
+// variables. Like in systemd, a word being an unbraced $VAR is replaced by the
+// words in the variable value while ${VAR} is replaced as a single word.
+func (u *systemdUnit) expand(command string) ([]string, error) {
+	words, err := splitWords(command)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	for _, word := range words {
+		if m := envVarRe.FindStringSubmatch(word); m != nil && m[0] == word && m[2] != "" {
+			args = append(args, strings.Fields(u.env[m[2]])...)
+			continue
+		}
+		args = append(args, envVarRe.ReplaceAllStringFunc(word, func(ref string) string {
+			return u.env[strings.Trim(ref, "${}")]
+		}))
+	}
+	return args, nil
+}
@@ -0,0 +1,28 @@
+package confreader
+
+import (
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestSystemdReader(t *testing.T) {
+	cnf, err := NewSystemdReader("testdata/systemd/mysqld.service")
+	tu.IsNil(t, err)
+
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"port":              "3307",
+		"datadir":           "/data/mysql",
+		"init-connect":      "SET NAMES utf8mb4",
+		"user":              "mysql",
+		"skip-name-resolve": "ON",
+		"max-connections":   "500",
+	})
+
+	origin, ok := cnf.Origin("max-connections")
+	tu.Assert(t, ok, "max-connections should have an origin")
+	tu.Equals(t, origin, Origin{Path: "testdata/systemd/mysqld.service.d/override.conf", Line: 3})
+
+	_, err = NewSystemdReader("testdata/groups/my.cnf")
+	tu.NotNil(t, err)
+}
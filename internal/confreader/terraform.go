@@ -0,0 +1,112 @@
+package confreader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// terraformParameterGroups are the Terraform resources defining RDS parameter
+// groups.
+var terraformParameterGroups = map[string]bool{"aws_db_parameter_group": true, "aws_rds_cluster_parameter_group": true}
+
+// NewTerraformReaders reads the RDS parameter groups defined in a Terraform
+// .tf file or in all the .tf files of a directory. It returns a config for
+// every aws_db_parameter_group and aws_rds_cluster_parameter_group resource,
+// keyed by the resource address, like aws_db_parameter_group.default.
+//
+// Only the parameter { name = ... value = ... } blocks are read and dynamic
+// blocks are ignored. Values are converted to strings like Terraform does.
+// Values that depend on something else, like var.max_connections or
+// "${local.prefix}_utf8", are Unknown, and parameters whose name is not a
+// constant are skipped. Like in NewRDSReader, formulas are evaluated using
+// the instance values.
+func NewTerraformReaders(path string, instance RDSInstance) (map[string]ConfigReader, error) {
+	path = cleanFilename(path)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read Terraform files")
+	}
+
+	files := []string{path}
+	if fi.IsDir() {
+		// Glob returns the files sorted.
+		files, _ = filepath.Glob(filepath.Join(path, "*.tf"))
+	}
+
+	configs := make(map[string]ConfigReader)
+	for _, filename := range files {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := hclsyntax.ParseConfig(data, filename, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, errors.Wrap(diags, "invalid Terraform file")
+		}
+		for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+			if b.Type != "resource" || len(b.Labels) != 2 || !terraformParameterGroups[b.Labels[0]] {
+				continue
+			}
+			address := b.Labels[0] + "." + b.Labels[1]
+			configs[address] = terraformParameterGroup(b, address, filename, data, instance)
+		}
+	}
+
+	return configs, nil
+}
+
+func terraformParameterGroup(resource *hclsyntax.Block, address, filename string, src []byte, instance RDSInstance) *Config {
+	cnf := &Config{
+		ConfigType: "rds",
+		EntriesMap: make(map[string]interface{}),
+		OriginsMap: make(map[string]Origin),
+	}
+	for _, b := range resource.Body.Blocks {
+		nameAttr, ok := b.Body.Attributes["name"]
+		if b.Type != "parameter" || !ok {
+			continue
+		}
+		name, ok := terraformString(nameAttr.Expr)
+		if !ok {
+			continue
+		}
+
+		var value interface{} = ""
+		if valueAttr, ok := b.Body.Attributes["value"]; ok {
+			if s, ok := terraformString(valueAttr.Expr); ok {
+				value = s
+				if v, err := evalRDSFormula(s, instance); err == nil {
+					value = v
+				}
+			} else {
+				value = Unknown(valueAttr.Expr.Range().SliceBytes(src))
+			}
+		}
+		cnf.EntriesMap[name] = value
+		cnf.Order = append(cnf.Order, name)
+		cnf.OriginsMap[name] = Origin{Path: filename, Line: b.TypeRange.Start.Line, Group: address}
+	}
+	return cnf
+}
+
+// terraformString returns the value of an expression converted to a string,
+// like Terraform does for string arguments, and false if it cannot be
+// evaluated without the rest of the configuration.
+func terraformString(expr hclsyntax.Expression) (string, bool) {
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || v.IsNull() || !v.IsWhollyKnown() {
+		return "", false
+	}
+	s, err := convert.Convert(v, cty.String)
+	if err != nil {
+		return "", false
+	}
+	return s.AsString(), true
+}
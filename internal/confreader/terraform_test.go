@@ -0,0 +1,60 @@
+package confreader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestTerraformReaders(t *testing.T) {
+	configs, err := NewTerraformReaders("testdata/terraform", RDSInstance{Memory: 16 << 30})
+	tu.IsNil(t, err)
+	tu.Equals(t, len(configs), 2)
+
+	prod := configs["aws_db_parameter_group.prod"]
+	tu.Assert(t, prod != nil, "aws_db_parameter_group.prod should be read")
+	tu.Equals(t, prod.Type(), "rds")
+	tu.Equals(t, prod.Entries(), map[string]interface{}{
+		"max_connections":         Unknown("var.max_connections"),
+		"innodb_buffer_pool_size": "12884901888",
+		"long_query_time":         "2",
+		"init_connect":            `SET NAMES "utf8mb4"`,
+		"character_set_server":    Unknown(`"${var.charset}"`),
+		"collation_server":        Unknown(`"%{if var.ci}utf8mb4_0900_ai_ci%{else}utf8mb4_bin%{endif}"`),
+		"sql_mode":                "{a}",
+		"autocommit":              "true",
+	})
+	origin, ok := prod.Origin("long_query_time")
+	tu.Assert(t, ok, "long_query_time should have an origin")
+	tu.Equals(t, origin, Origin{Path: "testdata/terraform/rds.tf", Line: 22, Group: "aws_db_parameter_group.prod"})
+
+	aurora := configs["aws_rds_cluster_parameter_group.aurora"]
+	tu.Assert(t, aurora != nil, "aws_rds_cluster_parameter_group.aurora should be read")
+	tu.Equals(t, aurora.Entries(), map[string]interface{}{"character_set_server": "utf8mb4"})
+
+	configs, err = NewTerraformReaders("testdata/terraform/aurora.tf", RDSInstance{})
+	tu.IsNil(t, err)
+	tu.Equals(t, len(configs), 1)
+}
+
+func TestTerraformReadersErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confdiff-terraform")
+	tu.IsNil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, src := range []string{
+		`resource "a" "b" {`,
+		`name = "unclosed`,
+		`/* unclosed comment`,
+		`resource "a" "b" }`,
+		`"label" {}`,
+	} {
+		filename := filepath.Join(dir, "main.tf")
+		tu.IsNil(t, ioutil.WriteFile(filename, []byte(src), 0644))
+		_, err := NewTerraformReaders(filename, RDSInstance{})
+		tu.NotNil(t, err)
+	}
+}
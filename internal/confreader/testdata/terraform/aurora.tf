@@ -0,0 +1,17 @@
+resource "aws_rds_cluster_parameter_group" "aurora" {
+  name        = "aurora-mysql8"
+  family      = "aurora-mysql8.0"
+  description = <<-EOT
+    Aurora cluster parameters
+  EOT
+
+  parameter {
+    name  = "character_set_server"
+    value = "utf8mb4"
+  }
+}
+
+resource "aws_db_instance" "db" {
+  identifier = "db-${var.env}"
+  parameter_group_name = aws_db_parameter_group.prod.name
+}
@@ -0,0 +1,63 @@
+# RDS parameter groups
+variable "max_connections" {
+  default = 500
+}
+
+resource "aws_db_parameter_group" "prod" {
+  name   = "prod-mysql80"
+  family = "mysql8.0"
+
+  parameter {
+    name  = "max_connections"
+    value = var.max_connections
+  }
+
+  parameter {
+    name         = "innodb_buffer_pool_size"
+    value        = "{DBInstanceClassMemory*3/4}"
+    apply_method = "pending-reboot"
+  }
+
+  /* Slow log settings */
+  parameter {
+    name  = "long_query_time"
+    value = 2 // seconds
+  }
+
+  parameter {
+    name = "init_connect"
+    value = "SET NAMES \"utf8mb4\""
+  }
+
+  parameter {
+    name  = "character_set_server"
+    value = "${var.charset}"
+  }
+
+  parameter {
+    name  = "collation_server"
+    value = "%{if var.ci}utf8mb4_0900_ai_ci%{else}utf8mb4_bin%{endif}"
+  }
+
+  parameter {
+    name  = "sql_mode"
+    value = "{a}"
+  }
+
+  parameter {
+    name  = "auto${"commit"}"
+    value = true
+  }
+
+  dynamic "parameter" {
+    for_each = var.extra_parameters
+    content {
+      name  = parameter.key
+      value = parameter.value
+    }
+  }
+
+  tags = {
+    Environment = "prod"
+  }
+}
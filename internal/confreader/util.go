@@ -3,11 +3,12 @@ package confreader
 import (
 	"os/user"
 	"path/filepath"
+	"strings"
 )
 
 func cleanFilename(filename string) string {
 	usr, _ := user.Current()
-	if filename[:2] == "~/" {
+	if strings.HasPrefix(filename, "~/") {
 		filename = filepath.Join(usr.HomeDir, filename[2:])
 	}
 	return filename
@@ -0,0 +1,99 @@
+package confreader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// variablesLineRe matches a line of SHOW VARIABLES output written by mysql in
+// batch mode, where the name and the value are separated by a tab.
+var variablesLineRe = regexp.MustCompile(`^[A-Za-z0-9_]+\t`)
+
+// NewVariablesReader reads a capture of the server variables like the ones in
+// support bundles, pt-stalk samples and the mysql-variables file saved by
+// pt-mysql-summary --save-samples. It understands:
+//   - the boxed tables written by mysqladmin variables and by mysql in
+//     interactive mode: | Variable_name | Value |
+//   - the tab separated output written by mysql -e "SHOW GLOBAL VARIABLES",
+//     with or without the column names (-N)
+//
+// The timestamp lines pt-stalk writes before every sample, like TS 1520000000,
+// and the [Warning] lines written by the clients are skipped. The config type
+// is mysql, so it is compared as a running server.
+func NewVariablesReader(filename string) (ConfigReader, error) {
+	filename = cleanFilename(filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read variables file")
+	}
+	defer f.Close()
+
+	return NewVariablesReaderFrom(f, filename)
+}
+
+// NewVariablesReaderFrom reads a capture of the server variables from r.
+// filename is used in error messages and origins. See NewVariablesReader.
+func NewVariablesReaderFrom(r io.Reader, filename string) (ConfigReader, error) {
+	cnf := &Config{
+		ConfigType: "mysql",
+		EntriesMap: make(map[string]interface{}),
+		OriginsMap: make(map[string]Origin),
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimRight(s.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		var name, value string
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "+-") || strings.HasPrefix(trimmed, "TS ") || strings.Contains(trimmed, ": [Warning] "):
+			continue
+		case strings.HasPrefix(trimmed, "|"):
+			fields := strings.Split(strings.TrimSuffix(trimmed, "|"), "|")
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("%s:%d: invalid table row %q", filename, lineNo, line)
+			}
+			name = strings.TrimSpace(fields[1])
+			// Values having a | are split in several fields.
+			value = strings.TrimSpace(strings.Join(fields[2:], "|"))
+		case variablesLineRe.MatchString(line):
+			tab := strings.IndexByte(line, '\t')
+			name, value = line[:tab], unescapeBatchValue(line[tab+1:])
+		default:
+			return nil, fmt.Errorf("%s:%d: invalid variable line %q", filename, lineNo, line)
+		}
+
+		if name == "Variable_name" {
+			continue
+		}
+		cnf.EntriesMap[name] = value
+		cnf.OriginsMap[name] = Origin{Path: filename, Line: lineNo}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(cnf.EntriesMap) == 0 {
+		return nil, fmt.Errorf("%s: there are no variables", filename)
+	}
+
+	return cnf, nil
+}
+
+// unescapeBatchValue replaces the escape sequences mysql writes in batch mode
+// for new lines, tabs, NUL characters and backslashes.
+func unescapeBatchValue(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\0`, "\x00", `\\`, `\`).Replace(value)
+}
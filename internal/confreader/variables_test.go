@@ -0,0 +1,41 @@
+package confreader
+
+import (
+	"strings"
+	"testing"
+
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestVariablesReader(t *testing.T) {
+	want := map[string]interface{}{
+		"autocommit":              "ON",
+		"init_connect":            "",
+		"innodb_buffer_pool_size": "134217728",
+		"max_connections":         "151",
+		"ssl_cipher":              "ECDHE-RSA-AES128-GCM-SHA256|AES256-SHA",
+	}
+
+	for _, filename := range []string{"testdata/variables/mysqladmin.txt", "testdata/variables/batch.txt"} {
+		cnf, err := NewVariablesReader(filename)
+		tu.IsNil(t, err)
+		tu.Equals(t, cnf.Type(), "mysql")
+		tu.Equals(t, cnf.Entries(), want)
+	}
+
+	cnf, err := NewVariablesReader("testdata/variables/batch-no-header.txt")
+	tu.IsNil(t, err)
+	tu.Equals(t, cnf.Entries(), map[string]interface{}{
+		"autocommit":           "ON",
+		"init_connect":         "SET NAMES utf8mb4;\nSET autocommit=1",
+		"innodb_data_home_dir": `C:\data\`,
+	})
+	origin, ok := cnf.Origin("init_connect")
+	tu.Assert(t, ok, "init_connect should have an origin")
+	tu.Equals(t, origin, Origin{Path: "testdata/variables/batch-no-header.txt", Line: 2})
+
+	for _, data := range []string{"", "[mysqld]\nport=3306\n", "| only_name |\n"} {
+		_, err = NewVariablesReaderFrom(strings.NewReader(data), "variables.txt")
+		tu.NotNil(t, err)
+	}
+}
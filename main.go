@@ -4,25 +4,52 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/user"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/Percona-Lab/pt-mysql-config-diff/internal/confreader"
-	"github.com/Percona-Lab/pt-mysql-config-diff/ptdsn"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
-	re = regexp.MustCompile("(?i)(\\d+)([kmg])")
+	re = regexp.MustCompile("(?i)^(\\d+)([kmgt])$")
 
 	app          = kingpin.New("pt-config-diff", "pt-config-diff")
-	cnfs         = app.Arg("cnf", "Config file or DNS in the form h=host,P=port,u=user,p=pass").Strings()
+	compareCmd   = app.Command("compare", "Compare config sources.").Default()
+	cnfs         = compareCmd.Arg("cnf", "Config sources: ["+strings.Join(schemes(), ":|")+":]<file or DSN in the form h=host,P=port,u=user,p=pass> or - to read stdin").Strings()
+	explainCmd   = app.Command("explain", "Show the description of a variable and its value in every source.")
+	explainVar   = explainCmd.Arg("variable", "Variable or option name, like innodb_buffer_pool_size.").Required().String()
+	explainSrcs  = explainCmd.Arg("cnf", "Config sources, like in compare. Default: the built-in defaults of --flavor and --server-version.").Strings()
+	generateCmd  = app.Command("generate-defaults", "Print the defaults of a mysqld binary, running it with --no-defaults --verbose --help.")
+	generateBin  = generateCmd.Arg("mysqld", "Path of the mysqld binary.").Required().String()
+	generateOut  = generateCmd.Flag("output", "Write the defaults to this file instead of the standard output.").Short('o').String()
+	generateCat  = generateCmd.Flag("catalog", "Mark the defaults that depend on the host, like the CPUs or the memory, as the built-in catalog does.").Bool()
+	upgradeCmd   = app.Command("upgrade-report", "Show how upgrading to another server version affects a config: variables removed, renamed, new and defaults changed.")
+	upgradeCur   = upgradeCmd.Arg("current", "Current config source, like in compare.").Required().String()
+	upgradeOld   = upgradeCmd.Arg("old-defaults", "Defaults of the current server version, like defaults:mysql-5.7.").Required().String()
+	upgradeNew   = upgradeCmd.Arg("new-defaults", "Defaults of the new server version, like defaults:mysql-8.0.").Required().String()
 	outputFormat = app.Flag("format", "Output format: text or json.").Default("text").String()
+	flavor       = app.Flag("flavor", "Server flavor used to choose the cnf groups to read and the built-in defaults: mysql, percona or mariadb.").Default("mysql").Enum("mysql", "percona", "mariadb")
+	srvVersion   = app.Flag("server-version", "Server version used to choose the cnf groups to read, like [mysqld-8.0], and the built-in defaults.").String()
+	groupSuffix  = app.Flag("defaults-group-suffix", "Also read the cnf groups having this suffix, like mysqld's --defaults-group-suffix.").String()
+	sysconfdir   = app.Flag("sysconfdir", "SYSCONFDIR mysqld was built with, used by defaults-path: sources.").Default("/usr/local/mysql/etc").String()
+	mysqlHome    = app.Flag("mysql-home", "MYSQL_HOME used by defaults-path: sources.").Envar("MYSQL_HOME").String()
+	extraFile    = app.Flag("defaults-extra-file", "Extra cnf file read by defaults-path: sources, like mysqld's --defaults-extra-file.").String()
+	homeDir      = app.Flag("home-dir", "Home directory of the user running mysqld, used by defaults-path: sources. Default: current user's home.").String()
+	rdsMemory    = app.Flag("rds-instance-memory", "DBInstanceClassMemory used to evaluate the formulas in rds: sources, like 16GB.").Bytes()
+	rdsVCPU      = app.Flag("rds-instance-vcpu", "DBInstanceVCPU used to evaluate the formulas in rds: sources.").Int64()
+	showOrigin   = app.Flag("show-origin", "Show where the value of every differing key was set, when known, including the file and line of cnf options and the ones they override.").Bool()
+	execTimeout  = app.Flag("defaults-timeout", "Time mysqld can run to print its defaults, for defaults:exec: sources and generate-defaults.").Default("30s").Duration()
+	defaultsDir  = app.Flag("defaults-cache", "Directory where the defaults printed by mysqld binaries are cached, by version and checksum.").String()
+	describe     = app.Flag("describe", "Show a one-line description under every differing key, taken from the defaults sources or the built-in defaults.").Bool()
 	version      = app.Flag("version", "Show version and exit").Bool()
 
 	Version   = "0.0.0."
@@ -34,7 +61,8 @@ var (
 
 func main() {
 
-	app.Parse(os.Args[1:])
+	command, err := app.Parse(stdinArgs(os.Args[1:]))
+	app.FatalIfError(err, "")
 
 	if *version {
 		fmt.Printf("Version   : %s\n", Version)
@@ -55,30 +83,160 @@ func main() {
 		if err != nil {
 			return nil, err
 		}
-		if db.Ping() != nil {
-			return nil, errors.Wrapf(err, "Cannot connect to MySQL at %q", dsn)
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return nil, errors.Wrap(err, "Cannot connect to MySQL")
 		}
 		return db, nil
 	}
 
-	configs, err := getConfigs(*cnfs, dbConnector)
+	groups := confreader.ServerGroups(*flavor, *srvVersion, *groupSuffix)
+
+	if *homeDir == "" {
+		if usr, err := user.Current(); err == nil {
+			*homeDir = usr.HomeDir
+		}
+	}
+	defaultFiles := confreader.DefaultFiles(*sysconfdir, *mysqlHome, *extraFile, *homeDir)
+
+	opts := &sourceOptions{
+		groups:        groups,
+		defaultFiles:  defaultFiles,
+		dbConnector:   dbConnector,
+		rdsInstance:   confreader.RDSInstance{Memory: int64(*rdsMemory), VCPU: *rdsVCPU},
+		exec:          confreader.ExecOptions{Timeout: *execTimeout, CacheDir: *defaultsDir},
+		flavor:        *flavor,
+		serverVersion: *srvVersion,
+		stdin:         os.Stdin,
+		log:           os.Stderr,
+	}
+
+	switch command {
+	case explainCmd.FullCommand():
+		runExplain(*explainVar, *explainSrcs, opts)
+		return
+	case generateCmd.FullCommand():
+		runGenerateDefaults(*generateBin, *generateOut, *generateCat, opts)
+		return
+	case upgradeCmd.FullCommand():
+		runUpgradeReport(*upgradeCur, *upgradeOld, *upgradeNew, opts)
+		return
+	}
+
+	configs, err := getConfigs(*cnfs, opts)
 	if err != nil {
 		log.Printf("Cannot get configs: %s", err.Error())
 		os.Exit(1)
 	}
 
+	configs = canonicalize(configs)
+
 	diffs := compare(configs)
 
+	var origins map[string][][]confreader.Origin
+	if *showOrigin {
+		origins = diffOrigins(diffs, configs)
+	}
+
+	var descriptions map[string]string
+	if *describe {
+		catalog, _ := catalogDefaults(opts)
+		descriptions = diffDescriptions(diffs, append(configs, catalog))
+	}
+
+	switch *outputFormat {
+	case "text":
+		printTextDiff(diffs, origins, descriptions)
+	case "json":
+		printJsonDiff(diffs, origins, descriptions)
+	}
+
+}
+
+// runGenerateDefaults saves the defaults printed by a mysqld binary, so they
+// can be compared later as a defaults: source. With catalog, the host
+// dependent defaults are marked, like in the files of the built-in catalog.
+func runGenerateDefaults(binary, output string, catalog bool, opts *sourceOptions) {
+	out, err := confreader.GenerateDefaults(binary, opts.exec)
+	if err != nil {
+		log.Printf("Cannot get the defaults: %s", err.Error())
+		os.Exit(1)
+	}
+	if catalog {
+		out = confreader.MarkHostDependent(out)
+	}
+
+	if output == "" {
+		os.Stdout.Write(out)
+		return
+	}
+	if err := ioutil.WriteFile(output, out, 0644); err != nil {
+		log.Printf("Cannot save the defaults: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+// runExplain prints the description of a variable and its value in every
+// source. Without sources, the built-in defaults are used.
+func runExplain(variable string, specs []string, opts *sourceOptions) {
+	if len(specs) == 0 {
+		_, name := catalogDefaults(opts)
+		specs = []string{"defaults:" + name}
+	}
+
+	configs, err := getConfigs(specs, opts)
+	if err != nil {
+		log.Printf("Cannot get configs: %s", err.Error())
+		os.Exit(1)
+	}
+
+	catalog, _ := catalogDefaults(opts)
+	e := explain(variable, specs, canonicalize(configs))
+	if e.Description == "" {
+		e.Description, _ = catalog.Description(e.Variable)
+	}
+
+	switch *outputFormat {
+	case "text":
+		e.printText()
+	case "json":
+		e.printJson()
+	}
+}
+
+// runUpgradeReport prints how upgrading from the old to the new defaults
+// affects the current config.
+func runUpgradeReport(current, oldDefaults, newDefaults string, opts *sourceOptions) {
+	configs, err := getConfigs([]string{current, oldDefaults, newDefaults}, opts)
+	if err != nil {
+		log.Printf("Cannot get configs: %s", err.Error())
+		os.Exit(1)
+	}
+	configs = canonicalize(configs)
+
+	report := upgrade(configs[0], configs[1], configs[2])
+
 	switch *outputFormat {
 	case "text":
-		printTextDiff(diffs)
+		report.printText()
 	case "json":
-		printJsonDiff(diffs)
+		report.printJson()
 	}
+}
 
+// diffOrigins returns, for every key in diffs, the provenance of its value in
+// each config. Unknown provenances are empty.
+func diffOrigins(diffs map[string][]interface{}, configs []confreader.ConfigReader) map[string][][]confreader.Origin {
+	origins := make(map[string][][]confreader.Origin)
+	for key := range diffs {
+		for _, cfg := range configs {
+			origins[key] = append(origins[key], cfg.Provenance(key))
+		}
+	}
+	return origins
 }
 
-func printTextDiff(diffs map[string][]interface{}) {
+func printTextDiff(diffs map[string][]interface{}, origins map[string][][]confreader.Origin, descriptions map[string]string) {
 	var keyLen, rightLen, leftLen int
 
 	for key, val := range diffs {
@@ -95,12 +253,34 @@ func printTextDiff(diffs map[string][]interface{}) {
 	format := fmt.Sprintf("%%%ds: %%%dv <-> %%%dv\n", keyLen, leftLen, rightLen)
 
 	for key, val := range diffs {
-		fmt.Printf(format, key, val[0], val[1])
+		fmt.Printf(format, key, fmt.Sprintf("%v", val[0]), fmt.Sprintf("%v", val[1]))
+		if description, ok := descriptions[key]; ok {
+			fmt.Printf("%*s  %s\n", keyLen, "", description)
+		}
+		for i, provenance := range origins[key] {
+			for _, origin := range provenance {
+				fmt.Printf("%*s  [%d] %s\n", keyLen, "", i+1, origin)
+			}
+		}
 	}
 }
 
-func printJsonDiff(diffs map[string][]interface{}) {
-	b, _ := json.MarshalIndent(diffs, "", "  ")
+func printJsonDiff(diffs map[string][]interface{}, origins map[string][][]confreader.Origin, descriptions map[string]string) {
+	var b []byte
+	if origins == nil && descriptions == nil {
+		b, _ = json.MarshalIndent(diffs, "", "  ")
+	} else {
+		type diffWithOrigins struct {
+			Values      []interface{}
+			Origins     [][]confreader.Origin `json:",omitempty"`
+			Description string                `json:",omitempty"`
+		}
+		withOrigins := make(map[string]diffWithOrigins)
+		for key, val := range diffs {
+			withOrigins[key] = diffWithOrigins{Values: val, Origins: origins[key], Description: descriptions[key]}
+		}
+		b, _ = json.MarshalIndent(withOrigins, "", "  ")
+	}
 	fmt.Println(string(b))
 }
 
@@ -133,9 +313,9 @@ func compare(configs []confreader.ConfigReader) map[string][]interface{} {
 	}
 
 	for i := 1; i < len(configs); i++ {
-		canSkipMissingLeftKey := (configs[0].Type() == "cnf" && configs[i].Type() != "cnf") || configs[0].Type() == "defaults"
+		canSkipMissingLeftKey := (isPartial(configs[0]) && !isPartial(configs[i])) || configs[0].Type() == "defaults"
 
-		canSkipMissingRightKey := (configs[i].Type() == "cnf" && configs[0].Type() != "cnf") || configs[i].Type() == "defaults"
+		canSkipMissingRightKey := (isPartial(configs[i]) && !isPartial(configs[0])) || configs[i].Type() == "defaults"
 
 		for leftkey, leftval := range configs[0].Entries() {
 			rightval, ok := configs[i].Get(leftkey)
@@ -146,6 +326,20 @@ func compare(configs []confreader.ConfigReader) map[string][]interface{} {
 				continue
 			}
 
+			// Values that cannot be known, like Terraform variables, are
+			// neither equal nor different.
+			if isUnknown(leftval) || isUnknown(rightval) {
+				continue
+			}
+
+			leftlist, rightlist := listValue(configs[0], leftkey, leftval), listValue(configs[i], leftkey, rightval)
+			if isMultiValued(leftlist) || isMultiValued(rightlist) {
+				if diff := diffValues(leftlist, rightlist); len(diff.Added)+len(diff.Removed) > 0 {
+					addDiff(diffs, leftkey, leftval, diff)
+				}
+				continue
+			}
+
 			leftval = adjustValue(leftval)
 			rightval = adjustValue(rightval)
 
@@ -167,6 +361,63 @@ func compare(configs []confreader.ConfigReader) map[string][]interface{} {
 	return diffs
 }
 
+// canonicalize renames the keys of all configs to the names used by SHOW
+// VARIABLES. Abbreviated option names are expanded using knownNames.
+func canonicalize(configs []confreader.ConfigReader) []confreader.ConfigReader {
+	known := knownNames(configs)
+
+	canonical := make([]confreader.ConfigReader, 0, len(configs))
+	for _, cfg := range configs {
+		canonical = append(canonical, confreader.Canonicalize(cfg, known))
+	}
+	return canonical
+}
+
+// knownNames returns the variables used to expand abbreviated option names:
+// the keys of the configs having the full list of variables, MySQL and
+// defaults, except the built-in ones, that are a subset.
+func knownNames(configs []confreader.ConfigReader) map[string]bool {
+	known := make(map[string]bool)
+	for _, cfg := range configs {
+		if cfg.Type() != "mysql" && cfg.Type() != "defaults" {
+			continue
+		}
+		if c, ok := cfg.(*confreader.Config); ok && c.Subset {
+			continue
+		}
+		for _, key := range cfg.Keys() {
+			known[key] = true
+		}
+	}
+	return known
+}
+
+// isPartial returns true if the config has only the variables that were set
+// explicitly, like a cnf file, instead of the full list of variables returned
+// by SHOW VARIABLES or a defaults file.
+func isPartial(cfg confreader.ConfigReader) bool {
+	return cfg.Type() != "mysql" && cfg.Type() != "defaults"
+}
+
+// stdinArgs replaces the - arguments by stdin: because kingpin doesn't pass
+// a lone dash to the arguments.
+func stdinArgs(args []string) []string {
+	result := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "-" {
+			arg = "stdin:"
+		}
+		result[i] = arg
+	}
+	return result
+}
+
+// isUnknown returns true for the values that cannot be known from a source.
+func isUnknown(val interface{}) bool {
+	_, ok := val.(confreader.Unknown)
+	return ok
+}
+
 func adjustValue(val interface{}) interface{} {
 	units := map[string]int64{
 		"k": 1024,
@@ -178,7 +429,6 @@ func adjustValue(val interface{}) interface{} {
 	switch val.(type) {
 	case string:
 		val := fmt.Sprintf("%v", val)
-		// var re = regexp.MustCompile("(?i)(\\d+)([kmg])")
 		if strings.ToLower(val) == "yes" || strings.ToLower(val) == "on" || strings.ToLower(val) == "true" {
 			return 1
 		}
@@ -200,67 +450,91 @@ func adjustValue(val interface{}) interface{} {
 	return val
 }
 
-func addDiff(diffs map[string][]interface{}, leftkey string, leftval, rightval interface{}) {
-	if _, ok := diffs[leftkey]; !ok {
-		diffs[leftkey] = append(diffs[leftkey], leftval)
+// setDiff holds the differences between the values of a multi-valued option
+// like replicate-do-db: the values Added on the right side and the values
+// Removed from it.
+type setDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+func (d setDiff) String() string {
+	var items []string
+	for _, v := range d.Added {
+		items = append(items, "+"+v)
 	}
-	diffs[leftkey] = append(diffs[leftkey], rightval)
+	for _, v := range d.Removed {
+		items = append(items, "-"+v)
+	}
+	return strings.Join(items, " ")
 }
 
-func getConfigs(cnfs []string, dbConnector func(string) (*sql.DB, error)) ([]confreader.ConfigReader, error) {
-	var configs []confreader.ConfigReader
+func isMultiValued(val interface{}) bool {
+	_, ok := val.([]string)
+	return ok
+}
 
-	for _, spec := range cnfs {
-		if _, err := os.Stat(spec); err == nil {
-			if cnf, err := getCNF(spec); err == nil {
-				configs = append(configs, cnf)
-			} else {
-				fmt.Println(err.Error())
-			}
-			continue
-		}
-		if cnf, err := getMySQL(spec, dbConnector); err == nil {
-			configs = append(configs, cnf)
-		} else {
-			fmt.Println(err.Error())
+// listValue returns the values of a cumulative option as a []string when cfg
+// joins them with commas, like SHOW VARIABLES and the cloud parameter groups
+// do. Other values are returned as they are.
+func listValue(cfg confreader.ConfigReader, key string, val interface{}) interface{} {
+	s, ok := val.(string)
+	if !ok || !confreader.IsCumulative(key) || !joinsLists[cfg.Type()] {
+		return val
+	}
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
 		}
 	}
-
-	return configs, nil
+	return values
 }
 
-func getCNF(filename string) (confreader.ConfigReader, error) {
-	cfg, err := confreader.NewCNFReader(filename)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Cannot read %s", filename)
-	}
+// joinsLists are the config types having the values of cumulative options
+// joined with commas in a single value.
+var joinsLists = map[string]bool{"mysql": true, "defaults": true, "rds": true, "cloudsql": true, "azure": true}
 
-	if len(cfg.Entries()) == 0 {
-		cfg, err = confreader.NewDefaultsParser(filename)
-		if err != nil {
-			return nil, errors.Wrapf(err, "Cannot read %s", filename)
+// diffValues compares the values of a multi-valued option. A single value is
+// treated as a list having only that value.
+func diffValues(leftval, rightval interface{}) setDiff {
+	left, right := valueSet(leftval), valueSet(rightval)
+	diff := setDiff{}
+
+	for v := range right {
+		if !left[v] {
+			diff.Added = append(diff.Added, v)
+		}
+	}
+	for v := range left {
+		if !right[v] {
+			diff.Removed = append(diff.Removed, v)
 		}
 	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
 
-	return cfg, nil
+	return diff
 }
 
-func getMySQL(dsns string, dbConnector func(string) (*sql.DB, error)) (confreader.ConfigReader, error) {
-	dsn := ptdsn.NewPTDSN(dsns)
-
-	db, err := dbConnector(dsn.String())
-	if err != nil {
-		return nil, fmt.Errorf("Cannot connect to the db %s", err.Error())
+func valueSet(val interface{}) map[string]bool {
+	values, ok := val.([]string)
+	if !ok {
+		values = []string{fmt.Sprintf("%v", val)}
 	}
-	if db == nil {
-		return nil, fmt.Errorf("Cannot connect to the database on %q", dsns)
-	}
-	defer db.Close()
 
-	cfg, err := confreader.NewMySQLReader(db)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot read the config variables: %s", err.Error())
+	set := make(map[string]bool)
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
 	}
+	return set
+}
 
-	return cfg, nil
+func addDiff(diffs map[string][]interface{}, leftkey string, leftval, rightval interface{}) {
+	if _, ok := diffs[leftkey]; !ok {
+		diffs[leftkey] = append(diffs[leftkey], leftval)
+	}
+	diffs[leftkey] = append(diffs[leftkey], rightval)
 }
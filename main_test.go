@@ -112,7 +112,7 @@ func TestReadCNFs(t *testing.T) {
 			"basedir":                           "/usr",
 			"bind-address":                      "127.0.0.1",
 			"datadir":                           "/var/lib/mysql",
-			"explicit_defaults_for_timestamp":   "true",
+			"explicit_defaults_for_timestamp":   "ON",
 			"innodb_buffer_pool_size":           "512M",
 			"innodb_flush_log_at_trx_commit":    "2",
 			"key_buffer_size":                   "512M",
@@ -243,3 +243,131 @@ func TestShowNonDefaults(t *testing.T) {
 	diff := compare([]confreader.ConfigReader{cnf, defaults})
 	tu.Equals(t, diff, want)
 }
+
+func TestCompareMultiValued(t *testing.T) {
+	mockConfig1 := &confreader.Config{
+		ConfigType: "cnf",
+		EntriesMap: map[string]interface{}{
+			"replicate_do_db": []string{"db1", "db2"},
+			"plugin_load_add": []string{"auth_pam.so"},
+			"binlog_do_db":    []string{"db1"},
+		},
+	}
+
+	mockConfig2 := &confreader.Config{
+		ConfigType: "cnf",
+		EntriesMap: map[string]interface{}{
+			"replicate_do_db": []string{"db3", "db1"},
+			"plugin_load_add": []string{"auth_pam.so"},
+			"binlog_do_db":    []string{"db1", "db2"},
+		},
+	}
+
+	want := map[string][]interface{}{
+		"replicate_do_db": []interface{}{[]string{"db1", "db2"}, setDiff{Added: []string{"db3"}, Removed: []string{"db2"}}},
+		"binlog_do_db":    []interface{}{[]string{"db1"}, setDiff{Added: []string{"db2"}}},
+	}
+
+	got := compare([]confreader.ConfigReader{mockConfig1, mockConfig2})
+	tu.Equals(t, got, want)
+
+	// SHOW VARIABLES joins the values with commas. Other single values are
+	// not split, since database names can have commas.
+	server := &confreader.Config{
+		ConfigType: "mysql",
+		EntriesMap: map[string]interface{}{
+			"replicate_do_db": "db1,db3",
+			"binlog_do_db":    "db1",
+		},
+	}
+	mockConfig3 := &confreader.Config{
+		ConfigType: "cnf",
+		EntriesMap: map[string]interface{}{
+			"replicate_do_db": "db1,db3",
+			"binlog_do_db":    []string{"db1,db2"},
+		},
+	}
+
+	got = compare([]confreader.ConfigReader{mockConfig2, server})
+	tu.Equals(t, got, map[string][]interface{}{
+		"binlog_do_db":    []interface{}{[]string{"db1", "db2"}, setDiff{Removed: []string{"db2"}}},
+		"plugin_load_add": []interface{}{[]string{"auth_pam.so"}, "<Missing>"},
+	})
+
+	got = compare([]confreader.ConfigReader{mockConfig1, mockConfig3})
+	tu.Equals(t, got, map[string][]interface{}{
+		"replicate_do_db": []interface{}{[]string{"db1", "db2"}, setDiff{Added: []string{"db1,db3"}, Removed: []string{"db1", "db2"}}},
+		"binlog_do_db":    []interface{}{[]string{"db1"}, setDiff{Added: []string{"db1,db2"}, Removed: []string{"db1"}}},
+		"plugin_load_add": []interface{}{[]string{"auth_pam.so"}, "<Missing>"},
+	})
+}
+
+func TestComparePersistedVsMySQL(t *testing.T) {
+	persisted := &confreader.Config{
+		ConfigType: "persisted",
+		EntriesMap: map[string]interface{}{
+			"max_connections": "500",
+			"sql_mode":        "STRICT_TRANS_TABLES",
+		},
+	}
+
+	mysql := &confreader.Config{
+		ConfigType: "mysql",
+		EntriesMap: map[string]interface{}{
+			"max_connections": "151",
+			"sql_mode":        "STRICT_TRANS_TABLES",
+			"port":            "3306",
+		},
+	}
+
+	want := map[string][]interface{}{
+		"max_connections": []interface{}{"500", "151"},
+	}
+
+	got := compare([]confreader.ConfigReader{persisted, mysql})
+	tu.Equals(t, got, want)
+}
+
+func TestReadMySQLVariablesInfo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	tu.IsNil(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SHOW GLOBAL VARIABLES").WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).
+		AddRow("max_connections", "500").
+		AddRow("port", "3306").
+		AddRow("version", "8.0.36"))
+
+	mock.ExpectQuery("SELECT (.+) FROM performance_schema.variables_info").WillReturnRows(
+		sqlmock.NewRows([]string{"VARIABLE_NAME", "VARIABLE_SOURCE", "VARIABLE_PATH", "SET_TIME", "SET_USER", "SET_HOST"}).
+			AddRow("max_connections", "DYNAMIC", "", "2024-01-10 10:00:00.000000", "root", "localhost").
+			AddRow("port", "EXPLICIT", "/etc/my.cnf", nil, nil, nil).
+			AddRow("version", "COMPILED", "", nil, nil, nil))
+
+	cnf, err := confreader.NewMySQLReader(db)
+	tu.IsNil(t, err)
+
+	origin, ok := cnf.Origin("max_connections")
+	tu.Assert(t, ok, "max_connections should have an origin")
+	tu.Equals(t, origin, confreader.Origin{Source: "DYNAMIC", SetTime: "2024-01-10 10:00:00.000000", SetUser: "root", SetHost: "localhost"})
+
+	origin, ok = cnf.Origin("port")
+	tu.Assert(t, ok, "port should have an origin")
+	tu.Equals(t, origin, confreader.Origin{Source: "EXPLICIT", Path: "/etc/my.cnf"})
+	tu.IsNil(t, mock.ExpectationsWereMet())
+}
+
+func TestCanonicalizeCatalog(t *testing.T) {
+	defaults, err := confreader.NewCatalogReader("mysql-8.0.36")
+	tu.IsNil(t, err)
+	cnf := &confreader.Config{
+		ConfigType: "cnf",
+		EntriesMap: map[string]interface{}{"log-error": "/var/log/mysql/error.log"},
+	}
+
+	// The catalog has log_error_verbosity but not log_error, which must not
+	// be taken as an abbreviation.
+	configs := canonicalize([]confreader.ConfigReader{cnf, defaults})
+	logError, _ := configs[0].Get("log_error")
+	tu.Equals(t, logError, "/var/log/mysql/error.log")
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Percona-Lab/pt-mysql-config-diff/internal/confreader"
+	"github.com/Percona-Lab/pt-mysql-config-diff/ptdsn"
+	"github.com/pkg/errors"
+)
+
+// sourceOptions holds the settings readers need besides the source itself.
+type sourceOptions struct {
+	// groups are the cnf groups to read.
+	groups []string
+	// defaultFiles are the cnf files read by defaults-path: sources.
+	defaultFiles []string
+	dbConnector  func(string) (*sql.DB, error)
+	// rdsInstance has the instance values used by rds: sources.
+	rdsInstance confreader.RDSInstance
+	// exec controls how mysqld runs for defaults:exec: sources.
+	exec confreader.ExecOptions
+	// flavor and serverVersion choose the catalog defaults when there is no
+	// running server to take them from.
+	flavor        string
+	serverVersion string
+	// server is the first config read from a running server, or a capture
+	// of its variables, whose version chooses the catalog defaults.
+	server confreader.ConfigReader
+	stdin  io.Reader
+	// stdinRead is set once a source has read stdin.
+	stdinRead bool
+	// log receives the messages about how sources were interpreted.
+	log io.Writer
+}
+
+// readerFactory returns the config for a source. arg is the part of the
+// source after the scheme prefix, like the file name in cnf:my.cnf.
+type readerFactory func(arg string, opts *sourceOptions) (confreader.ConfigReader, error)
+
+// readerFactories has the reader factory for every source scheme.
+var readerFactories = map[string]readerFactory{
+	"args":           getArgs,
+	"auto":           getPersisted,
+	"azure":          getAzure,
+	"cloudsql":       getCloudSQL,
+	"cmdline":        getCmdline,
+	"cnf":            getCNF,
+	"compose":        getCompose,
+	"defaults":       getDefaults,
+	"defaults-path":  getDefaultFiles,
+	"dsn":            getMySQL,
+	"git":            getGit,
+	"k8s":            getManifest,
+	"print-defaults": getPrintDefaults,
+	"rds":            getRDS,
+	"snapshot":       getSnapshot,
+	"stdin":          getStdin,
+	"systemd":        getSystemd,
+	"terraform":      getTerraform,
+	"variables":      getVariables,
+}
+
+// stdinReader returns the config read from the standard input.
+type stdinReader func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error)
+
+// stdinReaders has the reader for every format that can be read from the
+// standard input with stdin:<format>.
+var stdinReaders = map[string]stdinReader{
+	"auto": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewPersistedReaderFrom(r, stdinName)
+	},
+	"azure": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewAzureReaderFrom(r, stdinName)
+	},
+	"cloudsql": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewCloudSQLReaderFrom(r, stdinName)
+	},
+	"cmdline": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewCmdlineReaderFrom(r, stdinName)
+	},
+	"cnf": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewCNFReaderFrom(r, stdinName, opts.groups...)
+	},
+	"defaults": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewDefaultsParserFrom(r, stdinName)
+	},
+	"print-defaults": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewPrintDefaultsReaderFrom(r, stdinName)
+	},
+	"rds": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewRDSReaderFrom(r, stdinName, opts.rdsInstance)
+	},
+	"snapshot": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewSnapshotReaderFrom(r)
+	},
+	"variables": func(r io.Reader, opts *sourceOptions) (confreader.ConfigReader, error) {
+		return confreader.NewVariablesReaderFrom(r, stdinName)
+	},
+}
+
+// stdinName is the name of the standard input in origins and messages.
+const stdinName = "<stdin>"
+
+// getConfigs returns the configs for the given sources. A source is either:
+//   - <scheme>:<arg> where scheme is a key in readerFactories
+//   - - to read the standard input, whose format is detected automatically
+//   - a file name or a DSN, whose type is detected automatically
+//
+// Catalog defaults without a version, like defaults:mysql, are read after the
+// other sources so they can use the version of a running server.
+func getConfigs(specs []string, opts *sourceOptions) ([]confreader.ConfigReader, error) {
+	configs := make([]confreader.ConfigReader, len(specs))
+
+	var later []int
+	for i, spec := range specs {
+		if isServerDefaults(spec) {
+			later = append(later, i)
+			continue
+		}
+		cfg, err := getConfig(spec, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Cannot read %q", sourceName(spec))
+		}
+		if _, version := confreader.ServerVersion(cfg); opts.server == nil && cfg.Type() == "mysql" && version != "" {
+			opts.server = cfg
+		}
+		logUnknown(spec, cfg, opts)
+		configs[i] = cfg
+	}
+
+	for _, i := range later {
+		cfg, err := getConfig(specs[i], opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Cannot read %q", sourceName(specs[i]))
+		}
+		logUnknown(specs[i], cfg, opts)
+		configs[i] = cfg
+	}
+
+	return configs, nil
+}
+
+// logUnknown lists the variables of a source whose value cannot be known, like
+// the ones set with Terraform variables, since they are not compared.
+func logUnknown(spec string, cfg confreader.ConfigReader, opts *sourceOptions) {
+	var unknown []string
+	for key, value := range cfg.Entries() {
+		if u, ok := value.(confreader.Unknown); ok {
+			unknown = append(unknown, key+" = "+string(u))
+		}
+	}
+	if len(unknown) == 0 {
+		return
+	}
+	sort.Strings(unknown)
+	fmt.Fprintf(opts.log, "The values of these variables in %s cannot be known and are not compared: %s.\n",
+		sourceName(spec), strings.Join(unknown, ", "))
+}
+
+// isServerDefaults returns true for the catalog defaults sources whose version
+// is taken from the running server: defaults: and defaults:<flavor>.
+func isServerDefaults(spec string) bool {
+	if !strings.HasPrefix(spec, "defaults:") {
+		return false
+	}
+	arg := strings.TrimPrefix(spec, "defaults:")
+	return arg == "" || (hasString(confreader.CatalogFlavors, arg) && !isFile(arg))
+}
+
+func getConfig(spec string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	if spec == "-" {
+		return getStdin("", opts)
+	}
+
+	if i := strings.Index(spec, ":"); i > 0 {
+		if factory, ok := readerFactories[spec[:i]]; ok {
+			return factory(spec[i+1:], opts)
+		}
+	}
+
+	scheme, err := detectScheme(spec)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(opts.log, "Source %q detected as %s. Prefix it with one of %s: to choose its type.\n",
+		sourceName(spec), scheme, strings.Join(schemes(), ":, "))
+
+	return readerFactories[scheme](spec, opts)
+}
+
+// sourceName returns a source as it can be shown in the output, having the
+// password of DSNs, like p=secret, masked.
+func sourceName(spec string) string {
+	dsn := strings.TrimPrefix(spec, "dsn:")
+	if dsn == spec {
+		if i := strings.Index(spec, ":"); i > 0 {
+			if _, ok := readerFactories[spec[:i]]; ok {
+				return spec
+			}
+		}
+		if isFile(spec) || !strings.Contains(spec, "=") {
+			return spec
+		}
+	}
+
+	parts := strings.Split(dsn, ",")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "p=") {
+			parts[i] = "p=********"
+		}
+	}
+	return spec[:len(spec)-len(dsn)] + strings.Join(parts, ",")
+}
+
+// detectScheme guesses the scheme of a source without a prefix. Existing
+// files are detected by their contents and anything else is taken as a DSN.
+func detectScheme(spec string) (string, error) {
+	if _, err := os.Stat(spec); err != nil {
+		if filename, _ := splitSelector(spec); filename != spec && isFile(filename) {
+			return detectScheme(filename)
+		}
+		if strings.Contains(spec, "=") {
+			return "dsn", nil
+		}
+		return "", fmt.Errorf("there is no such file and it is not a DSN")
+	}
+
+	if filepath.Base(spec) == "mysqld-auto.cnf" {
+		return "auto", nil
+	}
+
+	data, err := ioutil.ReadFile(spec)
+	if err != nil {
+		return "", err
+	}
+	return confreader.DetectFormat(data), nil
+}
+
+// splitSelector splits a source like file.yaml#selector into the file name
+// and the selector of the part of the file to read.
+func splitSelector(spec string) (string, string) {
+	if i := strings.LastIndex(spec, "#"); i >= 0 {
+		return spec[:i], spec[i+1:]
+	}
+	return spec, ""
+}
+
+func isFile(filename string) bool {
+	fi, err := os.Stat(filename)
+	return err == nil && !fi.IsDir()
+}
+
+func schemes() []string {
+	var names []string
+	for name := range readerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getArgs(cmdline string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewArgsReader(cmdline)
+}
+
+func getCmdline(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewCmdlineReader(filename)
+}
+
+func getAzure(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewAzureReader(filename)
+}
+
+func getCloudSQL(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewCloudSQLReader(filename)
+}
+
+func getCNF(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewCNFReader(filename, opts.groups...)
+}
+
+// getCompose reads a compose file service. The service is chosen with a
+// #<service> suffix, like docker-compose.yml#mysql-1. The server image
+// variables it sets are not compared, so they are only listed in the log.
+func getCompose(spec string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	filename, service := splitSelector(spec)
+	cfg, err := confreader.NewComposeReader(filename, service)
+	if err != nil {
+		return nil, err
+	}
+
+	if c, ok := cfg.(*confreader.Config); ok && len(c.Environment) > 0 {
+		var vars []string
+		for name, value := range c.Environment {
+			vars = append(vars, name+"="+value)
+		}
+		sort.Strings(vars)
+		fmt.Fprintf(opts.log, "%s sets the server image variables %s. They are not mysqld options and are not compared.\n",
+			spec, strings.Join(vars, ", "))
+	}
+	return cfg, nil
+}
+
+// getDefaults reads the output of mysqld --verbose --help saved in a file or
+// printed by a mysqld binary, like in defaults:exec:/usr/sbin/mysqld, or, for
+// sources like defaults:mysql-8.0.36, the defaults in the catalog. The
+// closest version in the catalog is used, and the flavor and version can be
+// omitted, like in defaults:percona or defaults:, to take them from the
+// running server or from --flavor and --server-version.
+func getDefaults(spec string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	if strings.HasPrefix(spec, "exec:") {
+		return confreader.NewExecDefaultsReader(strings.TrimPrefix(spec, "exec:"), opts.exec)
+	}
+
+	flavor, version := spec, ""
+	if i := strings.IndexByte(spec, '-'); i >= 0 {
+		flavor, version = spec[:i], spec[i+1:]
+	}
+	if isFile(spec) || (spec != "" && !hasString(confreader.CatalogFlavors, flavor)) {
+		return confreader.NewDefaultsParser(spec)
+	}
+
+	if version == "" {
+		serverFlavor, serverVersion := opts.flavor, opts.serverVersion
+		if opts.server != nil {
+			serverFlavor, serverVersion = confreader.ServerVersion(opts.server)
+		}
+		if flavor == "" {
+			flavor = serverFlavor
+		}
+		version = serverVersion
+	}
+	if flavor == "" {
+		flavor = "mysql"
+	}
+	if version == "" {
+		return nil, fmt.Errorf("the server version is unknown. Use defaults:<flavor>-<version>, like defaults:mysql-8.0.36, " +
+			"or --server-version")
+	}
+
+	name, err := confreader.ClosestCatalogVersion(flavor, version)
+	if err != nil {
+		return nil, err
+	}
+	if name != spec {
+		fmt.Fprintf(opts.log, "Using the %s defaults for %s %s.\n", name, flavor, version)
+	}
+	cfg, err := confreader.NewCatalogReader(name)
+	if err != nil {
+		return nil, err
+	}
+	// Keys missing from defaults are not compared, so the variables missing
+	// from the catalog would silently not be compared.
+	fmt.Fprintf(opts.log, "The built-in %s defaults only have %d commonly tuned variables. Other variables are not compared.\n",
+		name, len(cfg.Keys()))
+	return cfg, nil
+}
+
+func hasString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func getDefaultFiles(root string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	if root == "" {
+		root = "/"
+	}
+	return confreader.NewDefaultFilesReader(root, opts.defaultFiles, opts.groups...)
+}
+
+func getPersisted(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewPersistedReader(filename)
+}
+
+func getPrintDefaults(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewPrintDefaultsReader(filename)
+}
+
+func getRDS(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewRDSReader(filename, opts.rdsInstance)
+}
+
+func getSnapshot(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewSnapshotReader(filename)
+}
+
+// getStdin reads the standard input in the given format. If format is empty,
+// it is detected from the contents.
+func getStdin(format string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	if opts.stdinRead {
+		return nil, fmt.Errorf("the standard input can be read only once")
+	}
+	opts.stdinRead = true
+
+	data, err := ioutil.ReadAll(opts.stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = confreader.DetectFormat(data)
+		fmt.Fprintf(opts.log, "Standard input detected as %s. Use stdin:<format> to choose its format.\n", format)
+	}
+
+	read, ok := stdinReaders[format]
+	if !ok {
+		return nil, fmt.Errorf("%s cannot be read from the standard input. Use one of %s", format, strings.Join(stdinFormats(), ", "))
+	}
+	return read(bytes.NewReader(data), opts)
+}
+
+func stdinFormats() []string {
+	var names []string
+	for name := range stdinReaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getSystemd(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewSystemdReader(filename)
+}
+
+// getManifest reads a config in a Kubernetes manifest. The config is chosen
+// with a #<kind>/<name>[/<key>] suffix, like cluster.yaml#ConfigMap/mysql/my.cnf.
+func getManifest(spec string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	filename, selector := splitSelector(spec)
+	return confreader.NewManifestReader(filename, selector, opts.groups...)
+}
+
+// getGit reads an option file as it was at a git revision, from a source like
+// git:HEAD~3:etc/my.cnf. The path is relative to the root of the repository in
+// the current directory, or to the current directory if it starts with ./.
+func getGit(spec string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	colon := strings.IndexByte(spec, ':')
+	if colon < 1 {
+		return nil, fmt.Errorf("the git source must be git:<revision>:<path>")
+	}
+	return confreader.NewGitCNFReader("", spec[:colon], spec[colon+1:], opts.groups...)
+}
+
+// getTerraform reads an RDS parameter group defined in a Terraform file or
+// directory. The resource is chosen with a #<address> suffix, like
+// rds.tf#aws_db_parameter_group.prod, which is optional if there is only one.
+func getTerraform(spec string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	path, address := splitSelector(spec)
+	configs, err := confreader.NewTerraformReaders(path, opts.rdsInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	if address == "" && len(configs) == 1 {
+		for _, cfg := range configs {
+			return cfg, nil
+		}
+	}
+	if cfg, ok := configs[address]; ok {
+		return cfg, nil
+	}
+
+	var addresses []string
+	for a := range configs {
+		addresses = append(addresses, a)
+	}
+	sort.Strings(addresses)
+	return nil, fmt.Errorf("choose one of the parameter groups in %s: %s", path, strings.Join(addresses, ", "))
+}
+
+func getVariables(filename string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	return confreader.NewVariablesReader(filename)
+}
+
+func getMySQL(dsns string, opts *sourceOptions) (confreader.ConfigReader, error) {
+	dsn := ptdsn.NewPTDSN(dsns)
+
+	db, err := opts.dbConnector(dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("Cannot connect to the db %s", err.Error())
+	}
+	if db == nil {
+		return nil, fmt.Errorf("Cannot connect to the database")
+	}
+	defer db.Close()
+
+	cfg, err := confreader.NewMySQLReader(db)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the config variables: %s", err.Error())
+	}
+
+	return cfg, nil
+}
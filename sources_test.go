@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/Percona-Lab/pt-mysql-config-diff/internal/confreader"
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestGetConfigSchemes(t *testing.T) {
+	var log bytes.Buffer
+	opts := &sourceOptions{
+		stdin: strings.NewReader("[mysqld]\nport=3307\n"),
+		log:   &log,
+	}
+
+	tests := []struct {
+		spec     string
+		wantType string
+		detected bool
+	}{
+		{"cnf:test/mysqld.cnf", "cnf", false},
+		{"test/mysqld.cnf", "cnf", true},
+		{"defaults:internal/confreader/testdata/defaults.txt", "defaults", false},
+		{"defaults:mysql-8.0.36", "defaults", false},
+		{"internal/confreader/testdata/defaults.txt", "defaults", true},
+		{"internal/confreader/testdata/mysqld-auto.cnf", "persisted", true},
+		{"auto:internal/confreader/testdata/mysqld-auto.cnf", "persisted", false},
+		{"snapshot:internal/confreader/testdata/want_defaults.json", "defaults", false},
+		{"print-defaults:internal/confreader/testdata/printdefaults/print-defaults.txt", "args", false},
+		{"internal/confreader/testdata/printdefaults/my_print_defaults.txt", "args", true},
+		{"internal/confreader/testdata/systemd/mysqld.service", "args", true},
+		{"internal/confreader/testdata/cmdline", "args", true},
+		{"args:mysqld --port=3307", "args", false},
+		{"compose:docker-compose.yml#mysql-1", "args", false},
+		{"internal/confreader/testdata/compose/docker-compose.yml#mariadb", "args", true},
+		{"k8s:internal/confreader/testdata/manifests/cluster.yaml#PerconaXtraDBCluster/cluster1", "cnf", false},
+		{"internal/confreader/testdata/manifests/cluster.yaml#ConfigMap/mysql-config/my.cnf", "cnf", true},
+		{"internal/confreader/testdata/cloud/rds-parameters.json", "rds", true},
+		{"terraform:internal/confreader/testdata/terraform#aws_db_parameter_group.prod", "rds", false},
+		{"terraform:internal/confreader/testdata/terraform/aurora.tf", "rds", false},
+		{"internal/confreader/testdata/terraform/rds.tf", "rds", true},
+		{"internal/confreader/testdata/cloud/cloudsql-instance.json", "cloudsql", true},
+		{"azure:internal/confreader/testdata/cloud/azure-parameters.json", "azure", false},
+		{"internal/confreader/testdata/variables/mysqladmin.txt", "mysql", true},
+		{"variables:internal/confreader/testdata/variables/batch.txt", "mysql", false},
+		{"-", "cnf", true},
+	}
+
+	for _, test := range tests {
+		log.Reset()
+		cfg, err := getConfig(test.spec, opts)
+		tu.IsNil(t, err)
+		tu.Equals(t, cfg.Type(), test.wantType)
+		tu.Equals(t, strings.Contains(log.String(), "detected as"), test.detected)
+	}
+
+	_, err := getConfig("cnf:internal/confreader/testdata/defaults.txt", opts)
+	tu.NotNil(t, err)
+
+	_, err = getConfig("docker-compose.yml", opts)
+	tu.NotNil(t, err)
+
+	_, err = getConfig("defaults:exec:no-such-mysqld", opts)
+	tu.NotNil(t, err)
+
+	_, err = getConfig("git:test/mysqld.cnf", opts)
+	tu.NotNil(t, err)
+
+	_, err = getConfig("terraform:internal/confreader/testdata/terraform", opts)
+	tu.NotNil(t, err)
+
+	_, err = getConfig("no_such_file", opts)
+	tu.NotNil(t, err)
+}
+
+func TestGetConfigsCatalog(t *testing.T) {
+	var log bytes.Buffer
+	opts := &sourceOptions{
+		flavor: "mysql",
+		stdin:  strings.NewReader("version\t8.0.35-27\nversion_comment\tPercona Server (GPL), Release 27\n"),
+		log:    &log,
+	}
+
+	configs, err := getConfigs([]string{"defaults:", "-"}, opts)
+	tu.IsNil(t, err)
+	tu.Equals(t, configs[0].Type(), "defaults")
+	tu.Equals(t, configs[1].Type(), "mysql")
+	tu.Assert(t, strings.Contains(log.String(), "Using the percona-8.0.36-28 defaults for percona 8.0.35-27."), log.String())
+
+	log.Reset()
+	cfg, err := getConfig("defaults:mysql-5.7", opts)
+	tu.IsNil(t, err)
+	userstat, _ := cfg.Get("userstat")
+	tu.Equals(t, userstat, nil)
+	tu.Assert(t, strings.Contains(log.String(), "Using the mysql-5.7.44 defaults for mysql 5.7."), log.String())
+	tu.Assert(t, strings.Contains(log.String(), "The built-in mysql-5.7.44 defaults only have"), log.String())
+
+	_, err = getConfigs([]string{"defaults:mariadb"}, &sourceOptions{log: &log})
+	tu.NotNil(t, err)
+
+	_, err = getConfig("defaults:mysql-8.1.0", opts)
+	tu.NotNil(t, err)
+}
+
+func TestGetConfigStdin(t *testing.T) {
+	tests := []struct {
+		spec     string
+		filename string
+		wantType string
+	}{
+		{"-", "test/mysqld.cnf", "cnf"},
+		{"stdin:cnf", "test/mysqld.cnf", "cnf"},
+		{"-", "internal/confreader/testdata/defaults.txt", "defaults"},
+		{"-", "internal/confreader/testdata/printdefaults/my_print_defaults.txt", "args"},
+		{"stdin:", "internal/confreader/testdata/variables/batch.txt", "mysql"},
+		{"stdin:variables", "internal/confreader/testdata/variables/mysqladmin.txt", "mysql"},
+		{"-", "internal/confreader/testdata/cloud/rds-parameters.json", "rds"},
+	}
+
+	for _, test := range tests {
+		data, err := ioutil.ReadFile(test.filename)
+		tu.IsNil(t, err)
+		opts := &sourceOptions{stdin: bytes.NewReader(data), log: ioutil.Discard}
+
+		cfg, err := getConfig(test.spec, opts)
+		tu.IsNil(t, err)
+		tu.Equals(t, cfg.Type(), test.wantType)
+
+		// The standard input can be read only once.
+		_, err = getConfig(test.spec, opts)
+		tu.NotNil(t, err)
+	}
+
+	opts := &sourceOptions{stdin: strings.NewReader("[mysqld]\nport=3307\n"), log: ioutil.Discard}
+	_, err := getConfig("stdin:terraform", opts)
+	tu.NotNil(t, err)
+
+	tu.Equals(t, stdinArgs([]string{"--format=json", "-", "my.cnf"}), []string{"--format=json", "stdin:", "my.cnf"})
+}
+
+func TestSourceName(t *testing.T) {
+	tu.Equals(t, sourceName("h=127.1,P=3306,u=root,p=secret"), "h=127.1,P=3306,u=root,p=********")
+	tu.Equals(t, sourceName("dsn:h=db,p=secret"), "dsn:h=db,p=********")
+	tu.Equals(t, sourceName("args:mysqld --port=3307"), "args:mysqld --port=3307")
+	tu.Equals(t, sourceName("test/mysqld.cnf"), "test/mysqld.cnf")
+}
+
+func TestGetConfigsHidesPassword(t *testing.T) {
+	var log bytes.Buffer
+	connectors := []func(string) (*sql.DB, error){
+		func(string) (*sql.DB, error) { return nil, nil },
+		func(string) (*sql.DB, error) { return nil, fmt.Errorf("connection refused") },
+	}
+
+	for _, connector := range connectors {
+		opts := &sourceOptions{dbConnector: connector, log: &log}
+		for _, spec := range []string{"dsn:h=127.0.0.1,P=1,u=root,p=s3cret", "h=127.0.0.1,P=1,u=root,p=s3cret"} {
+			_, err := getConfigs([]string{spec}, opts)
+			tu.NotNil(t, err)
+			tu.Assert(t, !strings.Contains(err.Error(), "s3cret"), err.Error())
+		}
+	}
+	tu.Assert(t, !strings.Contains(log.String(), "s3cret"), log.String())
+}
+
+func TestGetComposeEnvironment(t *testing.T) {
+	var log bytes.Buffer
+	cfg, err := getConfig("compose:internal/confreader/testdata/compose/docker-compose.yml#mysql", &sourceOptions{log: &log})
+	tu.IsNil(t, err)
+
+	_, ok := cfg.Get("MYSQL_ROOT_PASSWORD")
+	tu.Assert(t, !ok, "image variables should not be compared")
+	tu.Assert(t, strings.Contains(log.String(), "MYSQL_ALLOW_EMPTY_PASSWORD=yes, MYSQL_DATABASE=test, MYSQL_ROOT_PASSWORD=********."), log.String())
+
+	args, err := confreader.NewArgsReader("--port=3306")
+	tu.IsNil(t, err)
+	diffs := compare(canonicalize([]confreader.ConfigReader{cfg, args}))
+	_, ok = diffs["mysql_root_password"]
+	tu.Assert(t, !ok, "image variables should not be compared")
+}
+
+func TestGetConfigsUnknown(t *testing.T) {
+	var log bytes.Buffer
+	spec := "terraform:internal/confreader/testdata/terraform/rds.tf"
+	configs, err := getConfigs([]string{spec, "args:--max-connections=100 --long-query-time=5"}, &sourceOptions{log: &log})
+	tu.IsNil(t, err)
+	tu.Assert(t, strings.Contains(log.String(), "max_connections = var.max_connections"), log.String())
+
+	diffs := compare(canonicalize(configs))
+	_, ok := diffs["max_connections"]
+	tu.Assert(t, !ok, "unknown values should not be compared")
+	_, ok = diffs["long_query_time"]
+	tu.Assert(t, ok, "known values should be compared")
+}
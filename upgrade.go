@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Percona-Lab/pt-mysql-config-diff/internal/confreader"
+)
+
+// Kinds of changes in an upgrade report, in the order they are shown.
+const (
+	changeRemoved = "removed"
+	changeRenamed = "renamed"
+	changeDefault = "default changed"
+	changeNew     = "new"
+)
+
+var changeOrder = map[string]int{changeRemoved: 0, changeRenamed: 1, changeDefault: 2, changeNew: 3}
+
+// upgradeCategories maps variable name prefixes to the categories shown in
+// the upgrade report. The first matching prefix wins, so log_bin is listed as
+// Replication before the log_ prefix of Logging. Other variables are General.
+var upgradeCategories = []struct {
+	name     string
+	prefixes []string
+}{
+	{"InnoDB", []string{"innodb_"}},
+	{"Replication", []string{"binlog_", "log_bin", "sync_binlog", "gtid_", "enforce_gtid", "expire_logs",
+		"replica_", "slave_", "master_", "source_", "relay_log", "log_replica", "log_slave", "log_slow_replica",
+		"log_slow_slave", "rpl_", "sync_master", "sync_source", "sync_relay", "skip_replica", "skip_slave",
+		"init_replica", "init_slave", "server_id"}},
+	{"Logging", []string{"log_", "slow_", "general_log", "long_query_time"}},
+	{"Performance Schema", []string{"performance_schema"}},
+	{"Query cache", []string{"query_cache"}},
+	{"Character sets", []string{"character_set", "collation"}},
+	{"Security", []string{"ssl_", "tls_", "authentication_", "default_authentication", "password_",
+		"caching_sha2", "sha256_", "local_infile", "require_secure"}},
+	{"Optimizer", []string{"optimizer_", "eq_range", "range_"}},
+	{"Connections", []string{"max_connect", "max_user_connections", "thread_", "back_log", "connect_timeout",
+		"wait_timeout", "interactive_timeout"}},
+}
+
+// renameNotes explains the renames that need more than a new name.
+var renameNotes = map[string]string{
+	"expire_logs_days": "the value is in seconds: days * 86400",
+}
+
+// upgradeChange is a variable affected by an upgrade.
+type upgradeChange struct {
+	Variable string
+	Change   string
+	// Value is the value in the current config, if it sets the variable.
+	Value      interface{} `json:",omitempty"`
+	OldDefault interface{} `json:",omitempty"`
+	NewDefault interface{} `json:",omitempty"`
+	NewName    string      `json:",omitempty"`
+	Note       string      `json:",omitempty"`
+}
+
+// upgradeReport lists the variables affected by an upgrade, by category.
+type upgradeReport struct {
+	OldVersion string `json:",omitempty"`
+	NewVersion string `json:",omitempty"`
+	Categories map[string][]upgradeChange
+}
+
+// upgrade compares the defaults of the old and new server versions and
+// returns how they affect the current config:
+//
+//   - removed: variables the config sets that are not in the new version.
+//   - renamed: variables having a new name in the new version, see
+//     confreader.RenamedVariable. They are reported when the rename happened
+//     between the versions, or when the config uses the old name. Value is
+//     set if the config sets the old name.
+//   - default changed: variables whose default changed and that the config
+//     doesn't set, so the new default silently applies.
+//   - new: variables introduced by the new version.
+//
+// A variable is set by a partial config, like a cnf file, if it is in the
+// config, even if the old defaults don't have it. Configs having all the
+// variables, like SHOW VARIABLES, set the ones whose value differs from the
+// old default. The configs must be canonical.
+func upgrade(current, oldDefaults, newDefaults confreader.ConfigReader) upgradeReport {
+	report := upgradeReport{
+		OldVersion: defaultsVersion(oldDefaults),
+		NewVersion: defaultsVersion(newDefaults),
+		Categories: make(map[string][]upgradeChange),
+	}
+	reported := make(map[string]bool)
+	add := func(c upgradeChange) {
+		category := variableCategory(c.Variable)
+		report.Categories[category] = append(report.Categories[category], c)
+		reported[c.Variable] = true
+		if c.NewName != "" {
+			reported[c.NewName] = true
+		}
+	}
+
+	for _, name := range upgradeCandidates(current, oldDefaults) {
+		oldDefault, inOld := oldDefaults.Get(name)
+		newDefault, inNew := newDefaults.Get(name)
+		value, set := isSet(current, oldDefaults, name)
+		if !set {
+			value = nil
+		}
+
+		if newName, ok := confreader.RenamedVariable(name); ok {
+			_, hadNew := oldDefaults.Get(newName)
+			renamedDefault, hasNew := newDefaults.Get(newName)
+			if hasNew && (!hadNew || set || !inNew) {
+				add(upgradeChange{Variable: name, Change: changeRenamed, Value: value, OldDefault: oldDefault,
+					NewDefault: renamedDefault, NewName: newName, Note: renameNotes[name]})
+				continue
+			}
+		}
+
+		switch {
+		case set && !inNew:
+			add(upgradeChange{Variable: name, Change: changeRemoved, Value: value, OldDefault: oldDefault})
+		case inOld && inNew && !set && !sameValue(oldDefault, newDefault):
+			add(upgradeChange{Variable: name, Change: changeDefault, OldDefault: oldDefault, NewDefault: newDefault})
+		}
+	}
+
+	for _, name := range newDefaults.Keys() {
+		if _, ok := oldDefaults.Get(name); ok || reported[name] {
+			continue
+		}
+		newDefault, _ := newDefaults.Get(name)
+		value, set := isSet(current, oldDefaults, name)
+		if !set {
+			value = nil
+		}
+		add(upgradeChange{Variable: name, Change: changeNew, Value: value, NewDefault: newDefault})
+	}
+
+	for _, changes := range report.Categories {
+		sort.Slice(changes, func(i, j int) bool {
+			if changes[i].Change != changes[j].Change {
+				return changeOrder[changes[i].Change] < changeOrder[changes[j].Change]
+			}
+			return changes[i].Variable < changes[j].Variable
+		})
+	}
+	return report
+}
+
+// upgradeCandidates returns the variables that can be removed, renamed or
+// have a new default: the ones in the old defaults, the ones the current
+// config sets and the ones it has under an old name, sorted.
+func upgradeCandidates(current, oldDefaults confreader.ConfigReader) []string {
+	names := oldDefaults.Keys()
+	for _, name := range current.Keys() {
+		if _, ok := oldDefaults.Get(name); ok {
+			continue
+		}
+		_, renamed := confreader.RenamedVariable(name)
+		if _, set := isSet(current, oldDefaults, name); set || renamed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isSet returns the value of a variable in the current config and true if
+// the config sets it. See upgrade. Configs having all the variables don't set
+// the ones missing from the old defaults or whose old default depends on the
+// host, since their default is unknown.
+func isSet(current, oldDefaults confreader.ConfigReader, name string) (interface{}, bool) {
+	value, ok := current.Get(name)
+	if !ok {
+		return nil, false
+	}
+	if isPartial(current) {
+		return value, true
+	}
+	oldDefault, ok := oldDefaults.Get(name)
+	return value, ok && !isUnknown(oldDefault) && !sameValue(value, oldDefault)
+}
+
+// sameValue returns true if two values are the same once adjusted, so ON and
+// TRUE or 1M and 1048576 are equal.
+func sameValue(a, b interface{}) bool {
+	if isMultiValued(a) || isMultiValued(b) {
+		diff := diffValues(a, b)
+		return len(diff.Added)+len(diff.Removed) == 0
+	}
+	return adjustValue(a) == adjustValue(b)
+}
+
+// variableCategory returns the category of a variable in the upgrade report.
+func variableCategory(name string) string {
+	for _, category := range upgradeCategories {
+		for _, prefix := range category.prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return category.name
+			}
+		}
+	}
+	return "General"
+}
+
+// defaultsVersion returns the server version defaults were read from, if
+// known.
+func defaultsVersion(cfg confreader.ConfigReader) string {
+	if c, ok := cfg.(*confreader.Config); ok {
+		return c.Version
+	}
+	return ""
+}
+
+func (r upgradeReport) printText() {
+	if r.OldVersion != "" && r.NewVersion != "" {
+		fmt.Printf("Upgrade from %s to %s\n\n", r.OldVersion, r.NewVersion)
+	}
+	if len(r.Categories) == 0 {
+		fmt.Println("No changes found.")
+		return
+	}
+
+	categories := make([]string, 0, len(r.Categories))
+	for category := range r.Categories {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for i, category := range categories {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(category)
+		for _, c := range r.Categories[category] {
+			fmt.Printf("  %s\n", c)
+		}
+	}
+}
+
+func (c upgradeChange) String() string {
+	var s string
+	switch c.Change {
+	case changeRemoved:
+		s = fmt.Sprintf("%s: removed, set to %v", c.Variable, c.Value)
+	case changeRenamed:
+		s = fmt.Sprintf("%s: renamed to %s", c.Variable, c.NewName)
+		if c.OldDefault != nil && !sameValue(c.OldDefault, c.NewDefault) {
+			s += fmt.Sprintf(", default changed from %v to %v", c.OldDefault, c.NewDefault)
+		}
+		if c.Value != nil {
+			s += fmt.Sprintf(", set to %v", c.Value)
+		}
+	case changeDefault:
+		s = fmt.Sprintf("%s: default changed from %v to %v", c.Variable, c.OldDefault, c.NewDefault)
+	case changeNew:
+		s = fmt.Sprintf("%s: new, default %v", c.Variable, c.NewDefault)
+		if c.Value != nil {
+			s += fmt.Sprintf(", set to %v", c.Value)
+		}
+	}
+	if c.Note != "" {
+		s += " (" + c.Note + ")"
+	}
+	return s
+}
+
+func (r upgradeReport) printJson() {
+	b, _ := json.MarshalIndent(r, "", "  ")
+	fmt.Println(string(b))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Percona-Lab/pt-mysql-config-diff/internal/confreader"
+	tu "github.com/Percona-Lab/pt-mysql-config-diff/testutils"
+)
+
+func TestUpgrade(t *testing.T) {
+	oldDefaults, err := confreader.NewCatalogReader("mysql-5.7.44")
+	tu.IsNil(t, err)
+	newDefaults, err := confreader.NewCatalogReader("mysql-8.0.36")
+	tu.IsNil(t, err)
+	cnf := &confreader.Config{
+		ConfigType: "cnf",
+		EntriesMap: map[string]interface{}{
+			"innodb-file-format":       "Barracuda",
+			"query_cache_size":         "0",
+			"log-slave-updates":        "ON",
+			"innodb_autoinc_lock_mode": "2",
+			"max_allowed_packet":       "64M",
+			// Not in the mysql-5.7.44 catalog.
+			"tx_isolation":                   "READ-COMMITTED",
+			"innodb_locks_unsafe_for_binlog": "1",
+		},
+	}
+	configs := canonicalize([]confreader.ConfigReader{cnf, oldDefaults, newDefaults})
+
+	report := upgrade(configs[0], configs[1], configs[2])
+	tu.Equals(t, report.OldVersion, "5.7.44")
+	tu.Equals(t, report.NewVersion, "8.0.36")
+
+	tu.Equals(t, findChange(report, "innodb_file_format"), upgradeChange{
+		Variable: "innodb_file_format", Change: changeRemoved, Value: "Barracuda", OldDefault: "Barracuda",
+	})
+	tu.Equals(t, findChange(report, "query_cache_size").Change, changeRemoved)
+	tu.Equals(t, findChange(report, "log_slave_updates"), upgradeChange{
+		Variable: "log_slave_updates", Change: changeRenamed, Value: "ON", OldDefault: "FALSE", NewDefault: "TRUE",
+		NewName: "log_replica_updates",
+	})
+	tu.Equals(t, findChange(report, "slave_parallel_workers").NewName, "replica_parallel_workers")
+	tu.Equals(t, findChange(report, "tx_isolation"), upgradeChange{
+		Variable: "tx_isolation", Change: changeRenamed, Value: "READ-COMMITTED", NewDefault: "REPEATABLE-READ",
+		NewName: "transaction_isolation",
+	})
+	tu.Equals(t, findChange(report, "innodb_locks_unsafe_for_binlog"), upgradeChange{
+		Variable: "innodb_locks_unsafe_for_binlog", Change: changeRemoved, Value: "1",
+	})
+	tu.Equals(t, findChange(report, "expire_logs_days").NewName, "binlog_expire_logs_seconds")
+	tu.Equals(t, findChange(report, "innodb_flush_neighbors"), upgradeChange{
+		Variable: "innodb_flush_neighbors", Change: changeDefault, OldDefault: "1", NewDefault: "0",
+	})
+	tu.Equals(t, findChange(report, "innodb_redo_log_capacity").Change, changeNew)
+	tu.Equals(t, findChange(report, "collation_server"), upgradeChange{
+		Variable: "collation_server", Change: changeDefault, OldDefault: "latin1_swedish_ci", NewDefault: "utf8mb4_0900_ai_ci",
+	})
+	tu.Equals(t, findChange(report, "character_set_server").NewDefault, "utf8mb4")
+
+	// Variables set in the config, unset variables that were removed and
+	// rename targets are not reported.
+	for _, name := range []string{"innodb_autoinc_lock_mode", "max_allowed_packet", "query_cache_type", "binlog_expire_logs_seconds", "log_replica_updates"} {
+		tu.Equals(t, findChange(report, name), upgradeChange{})
+	}
+
+	tu.Equals(t, variableCategory("log_bin_basename"), "Replication")
+	tu.Equals(t, variableCategory("log_error_verbosity"), "Logging")
+	tu.Equals(t, variableCategory("table_open_cache"), "General")
+	tu.Equals(t, len(report.Categories["Query cache"]), 1)
+}
+
+func TestUpgradeServerVariables(t *testing.T) {
+	oldDefaults, err := confreader.NewCatalogReader("mysql-5.7.44")
+	tu.IsNil(t, err)
+	newDefaults, err := confreader.NewCatalogReader("mysql-8.0.36")
+	tu.IsNil(t, err)
+	configs := canonicalize([]confreader.ConfigReader{oldDefaults, oldDefaults, newDefaults})
+	server := configs[0].(*confreader.Config)
+	server.ConfigType = "mysql"
+	server.EntriesMap["innodb_flush_neighbors"] = "0"
+
+	report := upgrade(server, configs[1], configs[2])
+	// A server using the old defaults sets nothing, so nothing is removed.
+	tu.Equals(t, findChange(report, "query_cache_size"), upgradeChange{})
+	tu.Equals(t, findChange(report, "innodb_flush_neighbors"), upgradeChange{})
+	tu.Equals(t, findChange(report, "innodb_autoinc_lock_mode").Change, changeDefault)
+	tu.Equals(t, findChange(report, "log_slave_updates").Value, nil)
+
+	// Server variables missing from the old defaults are only reported when
+	// they were renamed.
+	server.EntriesMap["tx_isolation"] = "REPEATABLE-READ"
+	server.EntriesMap["hostname"] = "db1"
+	report = upgrade(server, configs[1], configs[2])
+	tu.Equals(t, findChange(report, "tx_isolation").NewName, "transaction_isolation")
+	tu.Equals(t, findChange(report, "tx_isolation").Value, nil)
+	tu.Equals(t, findChange(report, "hostname"), upgradeChange{})
+}
+
+func TestSameValue(t *testing.T) {
+	tu.Assert(t, sameValue("ON", "TRUE"), "ON and TRUE should be the same")
+	tu.Assert(t, sameValue("1M", "1048576"), "1M and 1048576 should be the same")
+	tu.Assert(t, sameValue("2g", "2147483648"), "2g and 2147483648 should be the same")
+	tu.Assert(t, !sameValue("utf8mb4_0900_ai_ci", "utf8mb4_general_ci"), "collations should differ")
+	tu.Assert(t, !sameValue("utf8mb3", "utf8mb4"), "character sets should differ")
+}
+
+func findChange(report upgradeReport, name string) upgradeChange {
+	for _, changes := range report.Categories {
+		for _, c := range changes {
+			if c.Variable == name {
+				return c
+			}
+		}
+	}
+	return upgradeChange{}
+}